@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/zanloy/bms-api/config"
 	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/reporter"
 	"github.com/zanloy/bms-api/router"
 	"github.com/zanloy/bms-api/url"
 	"k8s.io/klog"
@@ -70,6 +71,9 @@ func main() {
 	/* Setup URL checker */
 	go url.Start(config.Config.Urls, stopCh)
 
+	/* Setup report scheduler */
+	go reporter.Start(stopCh)
+
 	/* Setup router */
 	router := router.SetupRouter()
 
@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/mitchellh/mapstructure"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 	"github.com/zanloy/bms-api/models"
@@ -41,7 +44,7 @@ func Load() {
 		logger.Info().Msg(fmt.Sprintf("Loaded config file at %s.", viper.ConfigFileUsed()))
 	}
 
-	if err := viper.Unmarshal(&Config); err != nil {
+	if err := viper.Unmarshal(&Config, useJSONTags); err != nil {
 		logger.Fatal().Err(err).Msg("Failed to parse config file.")
 	}
 
@@ -49,6 +52,9 @@ func Load() {
 	viper.OnConfigChange(reload)
 
 	// Tell other components to load resources from Config
+	if err := loadHealthSettings(Config); err != nil {
+		logger.Fatal().Err(err).Msg("Invalid config file.")
+	}
 	wsrouter.LoadFilters(Config.Filters)
 
 	// Celebrate!
@@ -58,11 +64,96 @@ func Load() {
 func reload(e fsnotify.Event) {
 	logger.Info().Msg("Config file changed. Reloading...")
 	var newconfig = models.Config{}
-	if err := viper.Unmarshal(&newconfig); err == nil {
-		Config = newconfig
-		url.Reload(Config.Urls) // Reload our url checks
-		wsrouter.LoadFilters(Config.Filters)
-	} else {
+	if err := viper.Unmarshal(&newconfig, useJSONTags); err != nil {
 		logger.Err(err).Msg("Failed to parse config file. Retaining previous config.")
+		return
+	}
+	if err := loadHealthSettings(newconfig); err != nil {
+		logger.Err(err).Msg("Invalid config file. Retaining previous config.")
+		return
+	}
+
+	Config = newconfig
+	url.Reload(Config.Urls) // Reload our url checks
+	wsrouter.LoadFilters(Config.Filters)
+}
+
+// loadHealthSettings hands the parts of config used by the health checks to
+// the models package and logs the ones that can't be used. It returns an
+// error, before loading anything, for settings that would break the server if
+// we carried on with them.
+func loadHealthSettings(config models.Config) error {
+	// A bad retention tier would fail every save of a report.
+	for _, tier := range config.Retention {
+		if _, _, err := tier.Durations(); err != nil {
+			return err
+		}
+	}
+
+	if age := config.Velero.MaxBackupAge; age != "" {
+		if parsed, err := models.ParseDuration(age); err != nil || parsed <= 0 {
+			logger.Error().Err(err).Str("max_backup_age", age).Msg(fmt.Sprintf("Invalid velero.max_backup_age, using the default of %s.", defaultVeleroMaxBackupAge))
+		}
+	}
+	if config.IgnorePodsReplaceDefaults {
+		logger.Warn().Msg("ignore_pods_replace_defaults is set so the default ignore rules for Job pods, jenkins agents and the bms.io/ignore annotation are not used.")
+	}
+	if err := models.LoadConfig(config); err != nil {
+		logger.Err(err).Msg("Failed to load some of the health check settings.")
+	}
+
+	return nil
+}
+
+// useJSONTags tells viper to map config keys using the json tags on our models
+// so that keys like `max_reports` land in the right fields. Keys named after
+// the struct fields (e.g. `desc`) were used before, so those still load.
+func useJSONTags(dc *mapstructure.DecoderConfig) {
+	dc.TagName = "json"
+	dc.DecodeHook = mapstructure.ComposeDecodeHookFunc(fieldNameKeys, dc.DecodeHook)
+}
+
+// fieldNameKeys renames config keys that match a struct field name to the
+// field's json tag unless the json key is already set.
+func fieldNameKeys(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to.Kind() != reflect.Struct {
+		return data, nil
+	}
+
+	// Maps nested in lists come straight from the yaml parser with
+	// interface{} keys.
+	input := map[string]interface{}{}
+	switch value := data.(type) {
+	case map[string]interface{}:
+		input = value
+	case map[interface{}]interface{}:
+		for key, val := range value {
+			input[fmt.Sprint(key)] = val
+		}
+	default:
+		return data, nil
+	}
+
+	output := make(map[string]interface{}, len(input))
+	for key, value := range input {
+		output[key] = value
+	}
+	for idx := 0; idx < to.NumField(); idx++ {
+		field := to.Field(idx)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || strings.EqualFold(name, field.Name) {
+			continue
+		}
+		if _, ok := output[name]; ok {
+			continue
+		}
+		for key, value := range input {
+			if strings.EqualFold(key, field.Name) {
+				output[name] = value
+				delete(output, key)
+				break
+			}
+		}
 	}
+	return output, nil
 }
@@ -0,0 +1,62 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zanloy/bms-api/config"
+	"github.com/zanloy/bms-api/models"
+)
+
+func TestUseJSONTags(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		yaml     string
+		expected models.Config
+	}{
+		{
+			desc: "json keys",
+			yaml: "max_reports: 5\nurls:\n  - name: api\n    description: The api.\n    fail_true: true\n",
+			expected: models.Config{
+				MaxReports: 5,
+				Urls:       []models.URLCheck{{Name: "api", Desc: "The api.", FailTrue: true}},
+			},
+		},
+		{
+			desc: "field name keys",
+			yaml: "maxreports: 5\nurls:\n  - name: api\n    desc: The api.\n    failtrue: true\n",
+			expected: models.Config{
+				MaxReports: 5,
+				Urls:       []models.URLCheck{{Name: "api", Desc: "The api.", FailTrue: true}},
+			},
+		},
+		{
+			desc: "json key wins",
+			yaml: "urls:\n  - name: api\n    desc: Old.\n    description: New.\n",
+			expected: models.Config{
+				Urls: []models.URLCheck{{Name: "api", Desc: "New."}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			v := viper.New()
+			v.SetConfigType("yaml")
+			require.NoError(t, v.ReadConfig(strings.NewReader(tc.yaml)))
+
+			var got models.Config
+			require.NoError(t, v.Unmarshal(&got, config.UseJSONTags))
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestLoadHealthSettingsWithInvalidRetention(t *testing.T) {
+	err := config.LoadHealthSettings(models.Config{Retention: []models.RetentionTier{{Within: "48h"}, {Within: "30d", Every: "often"}}})
+	assert.EqualError(t, err, "Invalid retention every [often]: must be a duration like 1h, 1d, or 1w.")
+
+	assert.NoError(t, config.LoadHealthSettings(models.Config{Retention: []models.RetentionTier{{Within: "48h"}, {Within: "30d", Every: "1d"}}}))
+}
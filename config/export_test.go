@@ -0,0 +1,16 @@
+package config
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/zanloy/bms-api/models"
+)
+
+// UseJSONTags exposes useJSONTags to the config_test package.
+func UseJSONTags(dc *mapstructure.DecoderConfig) {
+	useJSONTags(dc)
+}
+
+// LoadHealthSettings exposes loadHealthSettings to the config_test package.
+func LoadHealthSettings(config models.Config) error {
+	return loadHealthSettings(config)
+}
@@ -1,6 +1,10 @@
 package config
 
-import "github.com/zanloy/bms-api/models"
+import (
+	"time"
+
+	"github.com/zanloy/bms-api/models"
+)
 
 func Filters() []models.Filter {
 	return Config.Filters
@@ -14,6 +18,30 @@ func MaxReports() int {
 	}
 }
 
+func RestartThreshold() int {
+	if Config.RestartThreshold == 0 {
+		return 5
+	} else {
+		return Config.RestartThreshold
+	}
+}
+
+func Retention() []models.RetentionTier {
+	return Config.Retention
+}
+
+func ReportSchedule() string {
+	return Config.ReportSchedule
+}
+
+func Storage() models.StorageConfig {
+	return Config.Storage
+}
+
+func SaveReports() bool {
+	return Config.SaveReports
+}
+
 func Namespace() string {
 	if Config.Namespace == "" {
 		return "bms"
@@ -21,3 +49,20 @@ func Namespace() string {
 		return Config.Namespace
 	}
 }
+
+func VeleroNamespace() string {
+	if Config.Velero.Namespace == "" {
+		return "velero"
+	} else {
+		return Config.Velero.Namespace
+	}
+}
+
+const defaultVeleroMaxBackupAge = 25 * time.Hour // A daily schedule with an hour to finish
+
+func VeleroMaxBackupAge() time.Duration {
+	if age, err := models.ParseDuration(Config.Velero.MaxBackupAge); err == nil && age > 0 {
+		return age
+	}
+	return defaultVeleroMaxBackupAge
+}
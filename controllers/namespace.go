@@ -19,18 +19,9 @@ func (ctl *NamespaceController) GetAllHealth(ctx *gin.Context) {
 		return
 	}
 
-	var ( // Setup vars for loop to keep memory allocation down.
-		ns     models.Namespace
-		report models.HealthReport
-	)
-
 	results := make([]models.Namespace, len(namespaces))
 	for idx, namespace := range namespaces {
-		ns = models.FromK8Namespace(namespace)
-		report = models.HealthReportForNamespace(*namespace, kubernetes.Factory)
-		ns.Healthy = report.Healthy
-		ns.Errors = report.Errors
-		results[idx] = ns
+		results[idx] = models.FromK8Namespace(namespace, kubernetes.Factory)
 	}
 
 	ctx.JSON(http.StatusOK, results)
@@ -43,10 +34,7 @@ func (ctl *NamespaceController) GetNS(ctx *gin.Context) {
 		return
 	}
 
-	ns := models.FromK8Namespace(namespace)
-	report := models.HealthReportForNamespace(*namespace, kubernetes.Factory)
-	ns.Healthy = report.Healthy
-	ns.Errors = report.Errors
+	ns := models.FromK8Namespace(namespace, kubernetes.Factory)
 
 	ctx.JSON(200, ns)
 }
@@ -1,122 +1,278 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/rs/zerolog/log"
-	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/config"
+	"github.com/zanloy/bms-api/export"
 	"github.com/zanloy/bms-api/models"
-	"github.com/zanloy/bms-api/url"
-	"k8s.io/apimachinery/pkg/labels"
+	"github.com/zanloy/bms-api/reporter"
+	"github.com/zanloy/bms-api/storage"
 )
 
 type ReportController struct{}
 
 // Create is an api endpoint that is called by an external entity (usually a
-// cron) to create a report and optionally store it in kubernetes.
+// cron) to create a report and optionally store it in kubernetes. The report
+// is stored if the save query param is true or, when absent, if save_reports
+// is set in the config. The tenant and env query params limit the report to a
+// single tenant and/or environment. Those reports are never stored since they
+// would replace the cluster wide report in the history. See renderReport for
+// the supported formats.
 func (ctl *ReportController) Create(ctx *gin.Context) {
-	report := models.NewReport()
-
-	// Nodes
-	if k8nodes, err := kubernetes.Nodes().List(labels.Everything()); err == nil {
-		// Make the nodes list the size of our nodes
-		nodelist := make([]models.Node, len(k8nodes))
-		// Iterate nodes
-		for idx, k8node := range k8nodes {
-			node := models.FromK8Node(*k8node)
-			if metrics, err := kubernetes.GetNodeMetrics(node); err == nil {
-				node.AddMetrics(metrics)
-			}
-			// Add to array
-			nodelist[idx] = node
+	opts := models.ReportOptions{
+		Tenant:      ctx.Query("tenant"),
+		Environment: ctx.Query("env"),
+	}
+
+	save := config.SaveReports() && !opts.Scoped()
+	if param, ok := ctx.GetQuery("save"); ok {
+		var err error
+		if save, err = strconv.ParseBool(param); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid value for save [%s]: must be true or false.", param)})
+			return
 		}
-		// Attach nodelist to report
-		report.Nodes = nodelist
-	} else {
-		err = fmt.Errorf("Failed to get nodes from kubernetes: %w", err)
-		logAndAppendError(err, &report)
-	}
-
-	// DaemonSets
-	if k8daemonsets, err := kubernetes.DaemonSets("").List(labels.Everything()); err == nil {
-		for _, k8daemonset := range k8daemonsets {
-			daemonset := models.FromK8DaemonSet(*k8daemonset)
-			if daemonset.Healthy != models.StatusHealthy {
-				report.UnhealthyDaemonSets = append(report.UnhealthyDaemonSets, daemonset)
-			}
+		if save && opts.Scoped() {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Reports limited to a tenant or env can not be saved."})
+			return
 		}
-	} else {
-		err = fmt.Errorf("Failed to get daemonsets from kubernetes: %w", err)
-		logAndAppendError(err, &report)
-	}
-
-	// Deployments
-	if k8deployments, err := kubernetes.Deployments("").List(labels.Everything()); err == nil {
-		// Iterate deployments
-		for _, k8deployment := range k8deployments {
-			deployment := models.FromK8Deployment(*k8deployment)
-			if deployment.Healthy != models.StatusHealthy {
-				report.UnhealthyDeployments = append(report.UnhealthyDeployments, deployment)
-			}
+	}
+
+	report := reporter.Generate(opts)
+
+	// Store it
+	if save {
+		reporter.Save(&report)
+	}
+
+	// Return results to client
+	renderReport(ctx, report)
+}
+
+// Delete is an api endpoint that will remove a stored report.
+func (ctl *ReportController) Delete(ctx *gin.Context) {
+	date, err := parseTimestamp(ctx.Param("timestamp"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := storage.DeleteReport(date); err != nil {
+		if errors.Is(err, storage.ReportNotFoundError) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
-	} else {
-		err = fmt.Errorf("Failed to get deployments from kubernetes: %w", err)
-		logAndAppendError(err, &report)
+		return
 	}
 
-	// Pods
-	if k8pods, err := kubernetes.Pods("").List(labels.Everything()); err == nil {
-		for _, k8pod := range k8pods {
-			pod := models.FromK8Pod(*k8pod)
-			if pod.Healthy != models.StatusHealthy {
-				report.UnhealthyPods = append(report.UnhealthyPods, pod)
-			}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Diff is an api endpoint that will compare two stored reports. When to is
+// omitted the newest report is used and when from is omitted the report
+// stored just before to is used.
+func (ctl *ReportController) Diff(ctx *gin.Context) {
+	reports, err := storage.GetReports()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var from, to *models.Report
+
+	if param := ctx.Query("to"); param != "" {
+		date, err := parseTimestamp(param)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if to = findReport(reports, date); to == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Report [%s] not found.", param)})
+			return
 		}
-	} else {
-		err = fmt.Errorf("Failed to get pods from kubernetes: %w", err)
-		logAndAppendError(err, &report)
+	} else if len(reports) > 0 {
+		to = &reports[len(reports)-1]
 	}
 
-	// StatefulSets
-	if k8statefulsets, err := kubernetes.StatefulSets("").List(labels.Everything()); err == nil {
-		for _, k8statefulset := range k8statefulsets {
-			statefulset := models.FromK8StatefulSet(*k8statefulset)
-			if statefulset.Healthy != models.StatusHealthy {
-				report.UnhealthyStatefulSets = append(report.UnhealthyStatefulSets, statefulset)
+	if param := ctx.Query("from"); param != "" {
+		date, err := parseTimestamp(param)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if from = findReport(reports, date); from == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Report [%s] not found.", param)})
+			return
+		}
+	} else if to != nil {
+		// Reports are stored oldest first so walk backwards
+		for idx := len(reports) - 1; idx >= 0; idx-- {
+			if reports[idx].Date.Before(to.Date) {
+				from = &reports[idx]
+				break
 			}
 		}
-	} else {
-		err = fmt.Errorf("Failed to get statefulsets from kubernetes: %w", err)
-		logAndAppendError(err, &report)
 	}
 
-	// URLs
-	report.URLs = url.GetTargets()
+	if from == nil || to == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "At least two stored reports are needed to create a diff."})
+		return
+	}
 
-	// Return results to client
-	ctx.JSON(http.StatusOK, report)
+	ctx.JSON(http.StatusOK, models.DiffReports(*from, *to))
 }
 
-// DISABLED
-/*
+// Get is an api endpoint that will return a single stored report. See
+// renderReport for the supported formats.
+func (ctl *ReportController) Get(ctx *gin.Context) {
+	date, err := parseTimestamp(ctx.Param("timestamp"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := storage.GetReport(date)
+	if err != nil {
+		if errors.Is(err, storage.ReportNotFoundError) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	renderReport(ctx, report)
+}
+
+// Schedule is an api endpoint that will return the state of the in-process
+// report scheduler.
+func (ctl *ReportController) Schedule(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, reporter.Status())
+}
+
+// List is an api endpoint that will return a summary of all stored reports.
 func (ctl *ReportController) List(ctx *gin.Context) {
 	reports, err := storage.ListReports()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, reports)
 }
-*/
-
-func logAndAppendError(err error, report *models.Report) {
-	logger = log.With().
-		Timestamp().
-		Str("component", "ReportController").
-		Logger()
-	logger.Err(err)
-	report.Errors = append(report.Errors, err.Error())
+
+// Trend is an api endpoint that returns the summary counts of every stored
+// report as a series for charting. The since query param limits it to reports
+// from after a unix timestamp or, given a duration (ex: 30d), from that long
+// ago.
+func (ctl *ReportController) Trend(ctx *gin.Context) {
+	var since time.Time
+	if param := ctx.Query("since"); param != "" {
+		var err error
+		if since, err = parseSince(param, time.Now()); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	summaries, err := storage.ListReports()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filtered := make([]models.ReportSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		if !summary.Date.Before(since) {
+			filtered = append(filtered, summary)
+		}
+	}
+
+	trend := models.TrendOf(filtered)
+	trend.Since = since
+	ctx.JSON(http.StatusOK, trend)
+}
+
+// findReport returns the report in reports addressed by date or nil.
+func findReport(reports []models.Report, date time.Time) *models.Report {
+	for idx := range reports {
+		if reports[idx].Date.Unix() == date.Unix() {
+			return &reports[idx]
+		}
+	}
+	return nil
+}
+
+// reportFormats maps the Accept header mime types to export formats.
+var reportFormats = map[string]string{
+	gin.MIMEJSON:    export.FormatJSON,
+	gin.MIMEHTML:    export.FormatHTML,
+	"text/markdown": export.FormatMarkdown,
+	"text/csv":      export.FormatCSV,
+}
+
+// renderReport writes the report in the format asked for by the format query
+// param (json, html, markdown, or csv) or, when absent, the Accept header.
+// JSON is the default. CSV needs a section query param naming the part of the
+// report to render.
+func renderReport(ctx *gin.Context, report models.Report) {
+	format, ok := ctx.GetQuery("format")
+	if !ok {
+		format = reportFormats[ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML, "text/markdown", "text/csv")]
+	}
+
+	switch strings.ToLower(format) {
+	case export.FormatJSON, "":
+		ctx.JSON(http.StatusOK, report)
+	case export.FormatHTML:
+		data, err := export.HTML(report)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", data)
+	case export.FormatMarkdown, "md":
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename(report, export.FormatMarkdown, "")))
+		ctx.Data(http.StatusOK, "text/markdown; charset=utf-8", export.Markdown(report))
+	case export.FormatCSV:
+		section := ctx.Query("section")
+		data, err := export.CSV(report, section)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename(report, export.FormatCSV, section)))
+		ctx.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid format [%s]: must be json, html, markdown, or csv.", format)})
+	}
+}
+
+// parseTimestamp converts the unix timestamp used to address stored reports
+// into a time.Time.
+func parseTimestamp(input string) (time.Time, error) {
+	timestamp, err := strconv.ParseInt(input, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid timestamp [%s]: must be a unix timestamp.", input)
+	}
+	return time.Unix(timestamp, 0), nil
+}
+
+// parseSince converts a unix timestamp or a duration before now into a
+// time.Time.
+func parseSince(input string, now time.Time) (time.Time, error) {
+	if since, err := parseTimestamp(input); err == nil {
+		return since, nil
+	}
+	if duration, err := models.ParseDuration(input); err == nil {
+		return now.Add(-duration), nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid since [%s]: must be a unix timestamp or a duration like 48h, 30d, or 52w.", input)
 }
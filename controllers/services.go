@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/models"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+type ServiceController struct{}
+
+func (ctl *ServiceController) GetAllHealth(ctx *gin.Context) {
+	// Get all Services
+	results, err := kubernetes.Services("").List(labels.Everything())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Err(err).Msg("An error occurred while trying to pull services from kubernetes.")
+		return
+	}
+
+	services := make([]models.HealthReport, len(results))
+	for idx, service := range results {
+		services[idx] = models.HealthReportForService(*service, kubernetes.Factory)
+	}
+
+	ctx.JSON(http.StatusOK, services)
+}
+
+func (ctl *ServiceController) WatchHealth(ctx *gin.Context) {
+	kubernetes.HealthUpdates.HandleRequestWithKeys(ctx.Writer, ctx.Request, map[string]interface{}{"kind": "service"})
+}
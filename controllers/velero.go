@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zanloy/bms-api/reporter"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+type VeleroController struct{}
+
+// GetBackups is an api endpoint that returns the health of every velero
+// backup.
+func (ctl *VeleroController) GetBackups(ctx *gin.Context) {
+	backups, _, err := reporter.VeleroHealth()
+	if err != nil {
+		veleroError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, backups)
+}
+
+// GetSchedules is an api endpoint that returns the health of every velero
+// schedule.
+func (ctl *VeleroController) GetSchedules(ctx *gin.Context) {
+	_, schedules, err := reporter.VeleroHealth()
+	if err != nil {
+		veleroError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedules)
+}
+
+func veleroError(ctx *gin.Context, err error) {
+	if k8errors.IsNotFound(err) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Velero does not appear to be installed in this cluster."})
+	} else {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/models"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+type PersistentVolumeClaimController struct{}
+
+func (ctl *PersistentVolumeClaimController) GetAllHealth(ctx *gin.Context) {
+	// Get all PersistentVolumeClaims
+	results, err := kubernetes.PersistentVolumeClaims("").List(labels.Everything())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Err(err).Msg("An error occurred while trying to pull persistentvolumeclaims from kubernetes.")
+		return
+	}
+
+	claims := make([]models.HealthReport, len(results))
+	for idx, claim := range results {
+		claims[idx] = models.HealthReportForPersistentVolumeClaim(*claim, kubernetes.Factory)
+	}
+
+	ctx.JSON(http.StatusOK, claims)
+}
+
+func (ctl *PersistentVolumeClaimController) WatchHealth(ctx *gin.Context) {
+	kubernetes.HealthUpdates.HandleRequestWithKeys(ctx.Writer, ctx.Request, map[string]interface{}{"kind": "persistentvolumeclaim"})
+}
+
+type PersistentVolumeController struct{}
+
+func (ctl *PersistentVolumeController) GetAllHealth(ctx *gin.Context) {
+	// Get all PersistentVolumes
+	results, err := kubernetes.PersistentVolumes().List(labels.Everything())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Err(err).Msg("An error occurred while trying to pull persistentvolumes from kubernetes.")
+		return
+	}
+
+	volumes := make([]models.HealthReport, len(results))
+	for idx, volume := range results {
+		volumes[idx] = models.HealthReportForPersistentVolume(*volume)
+	}
+
+	ctx.JSON(http.StatusOK, volumes)
+}
+
+func (ctl *PersistentVolumeController) WatchHealth(ctx *gin.Context) {
+	kubernetes.HealthUpdates.HandleRequestWithKeys(ctx.Writer, ctx.Request, map[string]interface{}{"kind": "persistentvolume"})
+}
@@ -0,0 +1,31 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/zanloy/bms-api/models"
+)
+
+// CSV renders a single section of the report as CSV with a header row.
+func CSV(report models.Report, section string) ([]byte, error) {
+	for _, s := range Sections(report) {
+		if s.Name != section {
+			continue
+		}
+
+		var buffer bytes.Buffer
+		writer := csv.NewWriter(&buffer)
+		writer.Write(s.Header)
+		writer.WriteAll(s.Rows) // Flushes for us.
+		if err := writer.Error(); err != nil {
+			return []byte{}, err
+		}
+
+		return buffer.Bytes(), nil
+	}
+
+	return []byte{}, fmt.Errorf("Unknown report section [%s]. Valid sections are: %s.", section, strings.Join(SectionNames(), ", "))
+}
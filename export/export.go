@@ -0,0 +1,217 @@
+// Package export renders a models.Report in formats meant for humans and
+// spreadsheets rather than the API.
+package export // import github.com/zanloy/bms-api/export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zanloy/bms-api/models"
+)
+
+// Supported formats.
+const (
+	FormatJSON     = "json"
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+	FormatCSV      = "csv"
+)
+
+// A Section is one part of a report laid out as a table.
+type Section struct {
+	Name   string // Matches the json key of the section in models.Report.
+	Title  string
+	Header []string
+	Rows   [][]string
+}
+
+// SectionNames returns the names of every section in the order they are
+// rendered.
+func SectionNames() []string {
+	sections := Sections(models.NewReport())
+	names := make([]string, len(sections))
+	for idx, section := range sections {
+		names[idx] = section.Name
+	}
+	return names
+}
+
+// Sections lays out every part of the report as a table.
+func Sections(report models.Report) []Section {
+	workloadHeader := []string{"Namespace", "Name", "Tenant", "Environment", "Healthy", "Errors"}
+
+	nodes := Section{
+		Name:  "nodes",
+		Title: "Nodes",
+		Header: []string{
+			"Name", "Healthy", "Conditions", "Kernel Version", "Kubelet Version",
+			"CPU Allocatable", "CPU Allocated", "CPU Allocated %", "CPU Limits", "CPU Limits %", "CPU Utilized", "CPU Utilized %",
+			"Memory Allocatable", "Memory Allocated", "Memory Allocated %", "Memory Limits", "Memory Limits %", "Memory Utilized", "Memory Utilized %",
+			"Errors",
+		},
+		Rows: make([][]string, len(report.Nodes)),
+	}
+	for idx, node := range report.Nodes {
+		row := []string{node.Name, string(node.Healthy), strings.Join(node.Conditions, ", "), node.KernelVersion, node.KubeletVersion}
+		row = append(row, resourceCells(node.CPU)...)
+		row = append(row, resourceCells(node.Memory)...)
+		nodes.Rows[idx] = append(row, joinErrors(node.Errors))
+	}
+
+	cronjobs := Section{
+		Name:   "unhealthy_cronjobs",
+		Title:  "Unhealthy CronJobs",
+		Header: []string{"Namespace", "Name", "Tenant", "Environment", "Schedule", "Suspended", "Healthy", "Errors"},
+		Rows:   make([][]string, len(report.UnhealthyCronJobs)),
+	}
+	for idx, cronjob := range report.UnhealthyCronJobs {
+		cronjobs.Rows[idx] = []string{cronjob.Namespace, cronjob.Name, cronjob.Tenant, cronjob.Environment, cronjob.Schedule, fmt.Sprint(cronjob.Suspended), string(cronjob.Healthy), joinErrors(cronjob.Errors)}
+	}
+
+	daemonsets := Section{Name: "unhealthy_daemonsets", Title: "Unhealthy DaemonSets", Header: workloadHeader, Rows: make([][]string, len(report.UnhealthyDaemonSets))}
+	for idx, daemonset := range report.UnhealthyDaemonSets {
+		daemonsets.Rows[idx] = []string{daemonset.Namespace, daemonset.Name, daemonset.Tenant, daemonset.Environment, string(daemonset.Healthy), joinErrors(daemonset.Errors)}
+	}
+
+	deployments := Section{Name: "unhealthy_deployments", Title: "Unhealthy Deployments", Header: workloadHeader, Rows: make([][]string, len(report.UnhealthyDeployments))}
+	for idx, deployment := range report.UnhealthyDeployments {
+		deployments.Rows[idx] = []string{deployment.Namespace, deployment.Name, deployment.Tenant, deployment.Environment, string(deployment.Healthy), joinErrors(deployment.Errors)}
+	}
+
+	jobs := Section{Name: "unhealthy_jobs", Title: "Unhealthy Jobs", Header: workloadHeader, Rows: make([][]string, len(report.UnhealthyJobs))}
+	for idx, job := range report.UnhealthyJobs {
+		jobs.Rows[idx] = []string{job.Namespace, job.Name, job.Tenant, job.Environment, string(job.Healthy), joinErrors(job.Errors)}
+	}
+
+	namespaces := Section{Name: "unhealthy_namespaces", Title: "Unhealthy Namespaces", Header: []string{"Name", "Tenant", "Environment", "Healthy", "Errors"}, Rows: make([][]string, len(report.UnhealthyNamespaces))}
+	for idx, namespace := range report.UnhealthyNamespaces {
+		namespaces.Rows[idx] = []string{namespace.Name, namespace.Tenant, namespace.Env, string(namespace.Healthy), joinErrors(namespace.Errors)}
+	}
+
+	pods := Section{Name: "unhealthy_pods", Title: "Unhealthy Pods", Header: workloadHeader, Rows: make([][]string, len(report.UnhealthyPods))}
+	for idx, pod := range report.UnhealthyPods {
+		pods.Rows[idx] = []string{pod.Namespace, pod.Name, pod.Tenant, pod.Environment, string(pod.Healthy), joinErrors(pod.Errors)}
+	}
+
+	services := Section{Name: "unhealthy_services", Title: "Unhealthy Services", Header: workloadHeader, Rows: make([][]string, len(report.UnhealthyServices))}
+	for idx, service := range report.UnhealthyServices {
+		services.Rows[idx] = []string{service.Namespace, service.Name, service.Tenant, service.Environment, string(service.Healthy), joinErrors(service.Errors)}
+	}
+
+	statefulsets := Section{Name: "unhealthy_statefulsets", Title: "Unhealthy StatefulSets", Header: workloadHeader, Rows: make([][]string, len(report.UnhealthyStatefulSets))}
+	for idx, statefulset := range report.UnhealthyStatefulSets {
+		statefulsets.Rows[idx] = []string{statefulset.Namespace, statefulset.Name, statefulset.Tenant, statefulset.Environment, string(statefulset.Healthy), joinErrors(statefulset.Errors)}
+	}
+
+	restarts := Section{
+		Name:   "restarts",
+		Title:  "Restarts",
+		Header: []string{"Namespace", "Pod", "Container", "Restart Count", "Reason", "Last Restart"},
+		Rows:   make([][]string, len(report.Restarts)),
+	}
+	for idx, restart := range report.Restarts {
+		restarts.Rows[idx] = []string{restart.Namespace, restart.Pod, restart.Container, fmt.Sprint(restart.RestartCount), restart.Reason, formatTime(restart.LastRestart)}
+	}
+
+	urls := Section{
+		Name:   "urlchecks",
+		Title:  "URL Checks",
+		Header: []string{"Name", "URL", "Healthy", "Text", "Checked", "Errors"},
+		Rows:   make([][]string, len(report.URLs)),
+	}
+	for idx, check := range report.URLs {
+		urls.Rows[idx] = []string{check.Name, check.Url, string(check.Healthy), check.Text, formatTime(check.Date), joinErrors(check.Errors)}
+	}
+
+	backups := Section{
+		Name:   "failed_backups",
+		Title:  "Failed Backups",
+		Header: []string{"Namespace", "Name", "Schedule", "Phase", "Started", "Completed", "Errors"},
+		Rows:   make([][]string, len(report.Velero.FailedBackups)),
+	}
+	for idx, backup := range report.Velero.FailedBackups {
+		backups.Rows[idx] = []string{backup.Namespace, backup.Name, backup.Schedule, backup.Phase, formatTime(backup.StartTimestamp), formatTime(backup.CompletionTimestamp), joinErrors(backup.Errors)}
+	}
+
+	schedules := Section{
+		Name:   "unhealthy_schedules",
+		Title:  "Unhealthy Backup Schedules",
+		Header: []string{"Namespace", "Name", "Schedule", "Phase", "Last Backup", "Last Successful Backup", "Errors"},
+		Rows:   make([][]string, len(report.Velero.UnhealthySchedules)),
+	}
+	for idx, schedule := range report.Velero.UnhealthySchedules {
+		schedules.Rows[idx] = []string{schedule.Namespace, schedule.Name, schedule.Schedule, schedule.Phase, formatTime(schedule.LastBackup), formatTime(schedule.LastSuccessfulBackup), joinErrors(schedule.Errors)}
+	}
+
+	return []Section{nodes, namespaces, cronjobs, daemonsets, deployments, jobs, pods, services, statefulsets, restarts, urls, backups, schedules}
+}
+
+// Title returns the heading of a rendered report.
+func Title(report models.Report) string {
+	title := "BMS Report"
+	switch {
+	case report.Tenant != "" && report.Environment != "":
+		title += fmt.Sprintf(" for %s (%s)", report.Tenant, report.Environment)
+	case report.Tenant != "":
+		title += " for " + report.Tenant
+	case report.Environment != "":
+		title += " for " + report.Environment
+	}
+	return title + " " + report.Date.Format(time.RFC1123)
+}
+
+// Filename returns the name a rendered report should be saved as.
+func Filename(report models.Report, format string, section string) string {
+	name := "bms-report"
+	for _, part := range []string{report.Tenant, report.Environment} {
+		if part != "" {
+			name += "-" + part
+		}
+	}
+	name += fmt.Sprintf("-%d", report.Date.Unix())
+	if section != "" {
+		name += "-" + section
+	}
+
+	switch format {
+	case FormatHTML:
+		return name + ".html"
+	case FormatMarkdown:
+		return name + ".md"
+	case FormatCSV:
+		return name + ".csv"
+	default:
+		return name + ".json"
+	}
+}
+
+func formatPercent(input float64) string {
+	return strconv.FormatFloat(input, 'f', -1, 64)
+}
+
+// resourceCells returns the cells for one resource of a node in the order of
+// the nodes header.
+func resourceCells(quantities models.ResourceQuantities) []string {
+	return []string{
+		quantities.Allocatable.String(),
+		quantities.Allocated.String(),
+		formatPercent(quantities.AllocatedPercent),
+		quantities.Limits.String(),
+		formatPercent(quantities.LimitsPercent),
+		quantities.Utilized.String(),
+		formatPercent(quantities.UtilizedPercent),
+	}
+}
+
+func formatTime(input time.Time) string {
+	if input.IsZero() {
+		return ""
+	}
+	return input.Format(time.RFC3339)
+}
+
+func joinErrors(errors []string) string {
+	return strings.Join(errors, "; ")
+}
@@ -0,0 +1,146 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zanloy/bms-api/export"
+	"github.com/zanloy/bms-api/models"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func genReport() models.Report {
+	report := models.NewReport()
+	report.Date = time.Unix(1600000000, 0).UTC()
+	report.Errors = []string{"Failed to get node metrics."}
+	report.Nodes = []models.Node{{
+		Name:           "node1",
+		Healthy:        models.StatusHealthy,
+		KubeletVersion: "v1.19.7",
+		CPU: models.ResourceQuantities{
+			Allocatable:      resource.MustParse("4"),
+			Allocated:        resource.MustParse("2"),
+			AllocatedPercent: 50,
+			Limits:           resource.MustParse("6"),
+			LimitsPercent:    150,
+		},
+		Memory: models.ResourceQuantities{
+			Allocatable:      resource.MustParse("16Gi"),
+			Allocated:        resource.MustParse("8Gi"),
+			AllocatedPercent: 50,
+		},
+	}}
+	report.UnhealthyPods = []models.Pod{{
+		Namespace: "app-dev",
+		Name:      "api-1",
+		Healthy:   models.StatusUnhealthy,
+		Errors:    []string{"Container api is in CrashLoopBackOff.", "Pod | is <not> ready."},
+	}}
+	report.URLs = []models.URLCheck{{Name: "grafana", Url: "https://grafana.example.com", Healthy: models.StatusHealthy, Text: "200 OK"}}
+	return report
+}
+
+func TestCSV(t *testing.T) {
+	report := genReport()
+
+	testCases := []struct {
+		desc     string
+		section  string
+		expected [][]string
+	}{{
+		desc:    "nodes",
+		section: "nodes",
+		expected: [][]string{
+			{
+				"Name", "Healthy", "Conditions", "Kernel Version", "Kubelet Version",
+				"CPU Allocatable", "CPU Allocated", "CPU Allocated %", "CPU Limits", "CPU Limits %", "CPU Utilized", "CPU Utilized %",
+				"Memory Allocatable", "Memory Allocated", "Memory Allocated %", "Memory Limits", "Memory Limits %", "Memory Utilized", "Memory Utilized %",
+				"Errors",
+			},
+			{"node1", "True", "", "", "v1.19.7", "4", "2", "50", "6", "150", "0", "0", "16Gi", "8Gi", "50", "0", "0", "0", "0", ""},
+		},
+	}, {
+		desc:    "unhealthy pods",
+		section: "unhealthy_pods",
+		expected: [][]string{
+			{"Namespace", "Name", "Tenant", "Environment", "Healthy", "Errors"},
+			{"app-dev", "api-1", "", "", "False", "Container api is in CrashLoopBackOff.; Pod | is <not> ready."},
+		},
+	}, {
+		desc:    "url checks",
+		section: "urlchecks",
+		expected: [][]string{
+			{"Name", "URL", "Healthy", "Text", "Checked", "Errors"},
+			{"grafana", "https://grafana.example.com", "True", "200 OK", "", ""},
+		},
+	}, {
+		desc:    "empty section",
+		section: "unhealthy_deployments",
+		expected: [][]string{
+			{"Namespace", "Name", "Tenant", "Environment", "Healthy", "Errors"},
+		},
+	}}
+
+	for _, tc := range testCases {
+		data, err := export.CSV(report, tc.section)
+		if assert.NoError(t, err, tc.desc) {
+			records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+			assert.NoError(t, err, tc.desc)
+			assert.Equal(t, tc.expected, records, tc.desc)
+		}
+	}
+
+	_, err := export.CSV(report, "bogus")
+	if assert.Error(t, err, "unknown section") {
+		assert.Contains(t, err.Error(), "nodes, unhealthy_namespaces, unhealthy_cronjobs, unhealthy_daemonsets", "unknown section should list the valid ones")
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	md := string(export.Markdown(genReport()))
+
+	assert.Contains(t, md, "# BMS Report Sun, 13 Sep 2020 12:26:40 UTC")
+	assert.Contains(t, md, "**Status:** False")
+	assert.Contains(t, md, "- Failed to get node metrics.")
+	assert.Contains(t, md, "| nodes | 1 |")
+	assert.Contains(t, md, "| app-dev | api-1 |  |  | False | Container api is in CrashLoopBackOff.; Pod \\| is <not> ready. |", "pipes in cells should be escaped")
+	assert.Contains(t, md, "## Unhealthy Deployments\n\nNone.")
+}
+
+func TestHTML(t *testing.T) {
+	data, err := export.HTML(genReport())
+	assert.NoError(t, err)
+	html := string(data)
+
+	assert.Contains(t, html, "<h1>BMS Report Sun, 13 Sep 2020 12:26:40 UTC</h1>")
+	assert.Contains(t, html, "Status: <span class=\"False\">False</span>")
+	assert.Contains(t, html, "<td>grafana</td>")
+	assert.Contains(t, html, "<td class=\"False\">False</td>", "health cells should be colored")
+	assert.Contains(t, html, "Pod | is &lt;not&gt; ready.", "cells should be html escaped")
+	assert.NotContains(t, html, "<link", "page should be self contained")
+	assert.NotContains(t, html, "<script", "page should be self contained")
+}
+
+func TestFilename(t *testing.T) {
+	report := genReport()
+	assert.Equal(t, "bms-report-1600000000.html", export.Filename(report, export.FormatHTML, ""))
+	assert.Equal(t, "bms-report-1600000000-nodes.csv", export.Filename(report, export.FormatCSV, "nodes"))
+
+	report.Tenant = "foo"
+	report.Environment = "prod"
+	assert.Equal(t, "bms-report-foo-prod-1600000000.md", export.Filename(report, export.FormatMarkdown, ""))
+}
+
+func TestTitle(t *testing.T) {
+	report := genReport()
+	assert.Equal(t, "BMS Report Sun, 13 Sep 2020 12:26:40 UTC", export.Title(report))
+
+	report.Tenant = "foo"
+	assert.Equal(t, "BMS Report for foo Sun, 13 Sep 2020 12:26:40 UTC", export.Title(report))
+
+	report.Environment = "prod"
+	assert.Equal(t, "BMS Report for foo (prod) Sun, 13 Sep 2020 12:26:40 UTC", export.Title(report))
+}
@@ -0,0 +1,109 @@
+package export
+
+import (
+	"bytes"
+	"html/template"
+	"sort"
+
+	"github.com/zanloy/bms-api/models"
+)
+
+// htmlTemplate is a self contained page (no external css/js) so it can be
+// attached to a change record and opened anywhere.
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"statusClass": statusClass,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+  body { font-family: sans-serif; margin: 2em; color: #222; }
+  table { border-collapse: collapse; margin-bottom: 2em; }
+  th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+  th { background: #eee; }
+  .True { color: #1a7f37; }
+  .False { color: #cf222e; font-weight: bold; }
+  .Warn, .Unknown { color: #9a6700; }
+  .errors { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+<p>Status: <span class="{{ .Healthy }}">{{ .Healthy }}</span></p>
+{{- if .Errors }}
+<h2>Errors</h2>
+<ul class="errors">
+{{- range .Errors }}
+  <li>{{ . }}</li>
+{{- end }}
+</ul>
+{{- end }}
+<h2>Summary</h2>
+<table>
+<tr><th>Count</th><th>Value</th></tr>
+{{- range .Counts }}
+<tr><td>{{ index . 0 }}</td><td>{{ index . 1 }}</td></tr>
+{{- end }}
+</table>
+{{- range .Sections }}
+<h2>{{ .Title }}</h2>
+{{- if .Rows }}
+<table>
+<tr>{{ range .Header }}<th>{{ . }}</th>{{ end }}</tr>
+{{- range .Rows }}
+<tr>{{ range . }}<td{{ with statusClass . }} class="{{ . }}"{{ end }}>{{ . }}</td>{{ end }}</tr>
+{{- end }}
+</table>
+{{- else }}
+<p>None.</p>
+{{- end }}
+{{- end }}
+</body>
+</html>
+`))
+
+// HTML renders the report as a self contained html page.
+func HTML(report models.Report) ([]byte, error) {
+	summary := report.Summary()
+	counts := summary.Counts
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rows := make([][]interface{}, len(keys))
+	for idx, key := range keys {
+		rows[idx] = []interface{}{key, counts[key]}
+	}
+
+	var buffer bytes.Buffer
+	err := htmlTemplate.Execute(&buffer, struct {
+		Title    string
+		Healthy  models.HealthyStatus
+		Errors   []string
+		Counts   [][]interface{}
+		Sections []Section
+	}{
+		Title:    Title(report),
+		Healthy:  summary.Healthy,
+		Errors:   report.Errors,
+		Counts:   rows,
+		Sections: Sections(report),
+	})
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// statusClass returns the css class used to color a cell holding a
+// models.HealthyStatus, or an empty string for any other cell.
+func statusClass(cell string) string {
+	switch models.HealthyStatus(cell) {
+	case models.StatusHealthy, models.StatusUnhealthy, models.StatusUnknown, models.StatusWarn:
+		return cell
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zanloy/bms-api/models"
+)
+
+// Markdown renders the report as GitHub flavored markdown suitable for pasting
+// into tickets.
+func Markdown(report models.Report) []byte {
+	var buffer bytes.Buffer
+
+	summary := report.Summary()
+	fmt.Fprintf(&buffer, "# %s\n\n", Title(report))
+	fmt.Fprintf(&buffer, "**Status:** %s\n\n", summary.Healthy)
+
+	if len(report.Errors) > 0 {
+		buffer.WriteString("## Errors\n\n")
+		for _, err := range report.Errors {
+			fmt.Fprintf(&buffer, "- %s\n", escapeMarkdown(err))
+		}
+		buffer.WriteString("\n")
+	}
+
+	buffer.WriteString("## Summary\n\n")
+	counts := summary.Counts
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rows := make([][]string, len(keys))
+	for idx, key := range keys {
+		rows[idx] = []string{key, fmt.Sprint(counts[key])}
+	}
+	writeMarkdownTable(&buffer, []string{"Count", "Value"}, rows)
+
+	for _, section := range Sections(report) {
+		fmt.Fprintf(&buffer, "## %s\n\n", section.Title)
+		if len(section.Rows) == 0 {
+			buffer.WriteString("None.\n\n")
+			continue
+		}
+		writeMarkdownTable(&buffer, section.Header, section.Rows)
+	}
+
+	return buffer.Bytes()
+}
+
+func writeMarkdownTable(buffer *bytes.Buffer, header []string, rows [][]string) {
+	writeMarkdownRow(buffer, header)
+	separator := make([]string, len(header))
+	for idx := range separator {
+		separator[idx] = "---"
+	}
+	writeMarkdownRow(buffer, separator)
+	for _, row := range rows {
+		writeMarkdownRow(buffer, row)
+	}
+	buffer.WriteString("\n")
+}
+
+func writeMarkdownRow(buffer *bytes.Buffer, cells []string) {
+	escaped := make([]string, len(cells))
+	for idx, cell := range cells {
+		escaped[idx] = escapeMarkdown(cell)
+	}
+	fmt.Fprintf(buffer, "| %s |\n", strings.Join(escaped, " | "))
+}
+
+// escapeMarkdown keeps cell contents from breaking out of a table row.
+func escapeMarkdown(input string) string {
+	input = strings.ReplaceAll(input, "|", `\|`)
+	return strings.ReplaceAll(input, "\n", " ")
+}
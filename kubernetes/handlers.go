@@ -6,8 +6,8 @@ import (
 	"github.com/zanloy/bms-api/models"
 	"gopkg.in/olahol/melody.v1"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -18,25 +18,93 @@ func setupInformers() {
 		DeleteFunc: handleDelete,
 	}
 
-	Factory.Extensions().V1beta1().
+	// CronJobs only moved to batch/v1 in Kubernetes 1.21. An informer for an
+	// API the cluster doesn't serve never syncs and would hang Start.
+	cronJobsServed = apiServed("batch/v1", "cronjobs")
+	models.SetCronJobsServed(cronJobsServed)
+	if cronJobsServed {
+		Factory.Batch().V1().
+			CronJobs().Informer().AddEventHandler(handlers)
+	} else {
+		logger.Warn().Msg("The cluster does not serve batch/v1 CronJobs so they will not be checked.")
+	}
+
+	Factory.Apps().V1().
 		DaemonSets().Informer().AddEventHandler(handlers)
 
-	Factory.Extensions().V1beta1().
+	Factory.Apps().V1().
 		Deployments().Informer().AddEventHandler(handlers)
 
+	Factory.Batch().V1().
+		Jobs().Informer().AddEventHandler(handlers)
+
 	Factory.Core().V1().
 		Namespaces().Informer().AddEventHandler(handlers)
 
 	Factory.Core().V1().
 		Nodes().Informer().AddEventHandler(handlers)
 
+	Factory.Core().V1().
+		PersistentVolumeClaims().Informer().AddEventHandler(handlers)
+
+	Factory.Core().V1().
+		PersistentVolumes().Informer().AddEventHandler(handlers)
+
 	Factory.Core().V1().
 		Pods().Informer().AddEventHandler(handlers)
 
+	Factory.Core().V1().
+		Services().Informer().AddEventHandler(handlers)
+
+	// The health of a service comes from its Endpoints so changes to them are
+	// broadcast as changes to the service. Adds are skipped: there is no
+	// previous state to compare against, so every service would look like it
+	// changed during the initial sync. New services are broadcast by the
+	// service handler instead.
+	Factory.Core().V1().
+		Endpoints().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: handleEndpoints,
+		DeleteFunc: func(obj interface{}) { handleEndpoints(obj, nil) },
+	})
+
 	Factory.Apps().V1().
 		StatefulSets().Informer().AddEventHandler(handlers)
 }
 
+// setupBMSConfigInformer broadcasts a namespace's health when its bms
+// ConfigMap changes since it can change the tenant, ignore rules and
+// thresholds used for everything in the namespace.
+func setupBMSConfigInformer() {
+	BMSConfigFactory.Core().V1().
+		ConfigMaps().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: handleBMSConfig,
+		UpdateFunc: func(prevObj interface{}, obj interface{}) {
+			prev, prevOk := prevObj.(*corev1.ConfigMap)
+			curr, currOk := obj.(*corev1.ConfigMap)
+			if prevOk && currOk && prev.ResourceVersion == curr.ResourceVersion {
+				// Nothing changed, this is just a resync.
+				return
+			}
+			handleBMSConfig(obj)
+		},
+		DeleteFunc: handleBMSConfig,
+	})
+}
+
+func handleBMSConfig(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	configmap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		logger.Debug().Interface("object", obj).Msg("Failed to assert type of object.")
+		return
+	}
+
+	broadcastNamespaceHealth(configmap.Namespace)
+}
+
 type filterFunc func(*melody.Session) bool
 
 func filterKind(s *melody.Session, kind string) bool {
@@ -56,6 +124,17 @@ func filterKind(s *melody.Session, kind string) bool {
 
 func filterAllowAll(s *melody.Session) bool { return true }
 
+// filterFor returns the filter for sessions watching kind.
+func filterFor(kind string) filterFunc {
+	return func(s *melody.Session) bool {
+		return filterKind(s, kind)
+	}
+}
+
+func filterCronJob(s *melody.Session) bool {
+	return filterKind(s, "cronjob")
+}
+
 func filterDaemonSet(s *melody.Session) bool {
 	return filterKind(s, "daemonset")
 }
@@ -64,6 +143,18 @@ func filterDeployment(s *melody.Session) bool {
 	return filterKind(s, "deployment")
 }
 
+func filterJob(s *melody.Session) bool {
+	return filterKind(s, "job")
+}
+
+func filterPersistentVolume(s *melody.Session) bool {
+	return filterKind(s, "persistentvolume")
+}
+
+func filterPersistentVolumeClaim(s *melody.Session) bool {
+	return filterKind(s, "persistentvolumeclaim")
+}
+
 func filterPod(s *melody.Session) bool {
 	return filterKind(s, "pod")
 }
@@ -76,6 +167,10 @@ func filterNode(s *melody.Session) bool {
 	return filterKind(s, "node")
 }
 
+func filterService(s *melody.Session) bool {
+	return filterKind(s, "service")
+}
+
 func filterStatefulSet(s *melody.Session) bool {
 	return filterKind(s, "statefulset")
 }
@@ -141,13 +236,13 @@ func handleAdd(obj interface{}) {
 			name = typed.Name
 			report = models.HealthReportForStatefulSet(*typed)
 			filter = filterStatefulSet
-		case *extensionsv1beta1.DaemonSet:
+		case *appsv1.DaemonSet:
 			kind = "daemonset"
 			namespace = typed.Namespace
 			name = typed.Name
 			report = models.HealthReportForDaemonSet(*typed)
 			filter = filterDaemonSet
-		case *extensionsv1beta1.Deployment:
+		case *appsv1.Deployment:
 			kind = "deployment"
 			namespace = typed.Namespace
 			name = typed.Name
@@ -162,6 +257,7 @@ func handleAdd(obj interface{}) {
 		logger.Err(err)
 		return
 	}
+	filter = filterFor(report.Kind)
 
 	update := models.HealthUpdate{
 		Action:    "add",
@@ -216,13 +312,13 @@ func handleUpdate(prevObj interface{}, obj interface{}) {
 			name = typed.Name
 			report = models.HealthReportForStatefulSet(*typed)
 			filter = filterStatefulSet
-		case *extensionsv1beta1.DaemonSet:
+		case *appsv1.DaemonSet:
 			kind = "daemonset"
 			namespace = typed.Namespace
 			name = typed.Name
 			report = models.HealthReportForDaemonSet(*typed)
 			filter = filterDaemonSet
-		case *extensionsv1beta1.Deployment:
+		case *appsv1.Deployment:
 			kind = "deployment"
 			namespace = typed.Namespace
 			name = typed.Name
@@ -237,6 +333,7 @@ func handleUpdate(prevObj interface{}, obj interface{}) {
 		logger.Err(err)
 		return
 	}
+	filter = filterFor(report.Kind)
 
 	if report.Healthy != prevReport.Healthy {
 		update := models.HealthUpdate{
@@ -263,6 +360,18 @@ func handleDelete(obj interface{}) {
 	)
 
 	switch typed := obj.(type) {
+	case *batchv1.CronJob:
+		kind = "cronjob"
+		namespace = typed.Namespace
+		name = typed.Name
+		report = models.HealthReportForCronJob(*typed, Factory)
+		filter = filterCronJob
+	case *batchv1.Job:
+		kind = "job"
+		namespace = typed.Namespace
+		name = typed.Name
+		report = models.HealthReportForJob(*typed, Factory)
+		filter = filterJob
 	case *corev1.Namespace:
 		kind = "namespace"
 		namespace = ""
@@ -275,25 +384,43 @@ func handleDelete(obj interface{}) {
 		name = typed.Name
 		report = models.HealthReportForNode(*typed)
 		filter = filterNode
+	case *corev1.PersistentVolume:
+		kind = "persistentvolume"
+		namespace = ""
+		name = typed.Name
+		report = models.HealthReportForPersistentVolume(*typed)
+		filter = filterPersistentVolume
+	case *corev1.PersistentVolumeClaim:
+		kind = "persistentvolumeclaim"
+		namespace = typed.Namespace
+		name = typed.Name
+		report = models.HealthReportForPersistentVolumeClaim(*typed, Factory)
+		filter = filterPersistentVolumeClaim
 	case *corev1.Pod:
 		kind = "pod"
 		namespace = typed.Namespace
 		name = typed.Name
 		report = models.HealthReportForPod(*typed)
 		filter = filterPod
+	case *corev1.Service:
+		kind = "service"
+		namespace = typed.Namespace
+		name = typed.Name
+		report = models.HealthReportForService(*typed, Factory)
+		filter = filterService
 	case *appsv1.StatefulSet:
 		kind = "statefulset"
 		namespace = typed.Namespace
 		name = typed.Name
 		report = models.HealthReportForStatefulSet(*typed)
 		filter = filterStatefulSet
-	case *extensionsv1beta1.DaemonSet:
+	case *appsv1.DaemonSet:
 		kind = "daemonset"
 		namespace = typed.Namespace
 		name = typed.Name
 		report = models.HealthReportForDaemonSet(*typed)
 		filter = filterDaemonSet
-	case *extensionsv1beta1.Deployment:
+	case *appsv1.Deployment:
 		kind = "deployment"
 		namespace = typed.Namespace
 		name = typed.Name
@@ -318,3 +445,43 @@ func handleDelete(obj interface{}) {
 	HealthUpdates.BroadcastFilter(update.ToMsg(), filter)
 	broadcastNamespaceHealth(namespace)
 }
+
+// handleEndpoints broadcasts a change in the health of the service that owns
+// the Endpoints. obj is nil when they were deleted.
+func handleEndpoints(prevObj interface{}, obj interface{}) {
+	prev, _ := prevObj.(*corev1.Endpoints)
+	curr, _ := obj.(*corev1.Endpoints)
+
+	var namespace, name string
+	switch {
+	case curr != nil:
+		namespace, name = curr.Namespace, curr.Name
+	case prev != nil:
+		namespace, name = prev.Namespace, prev.Name
+	default:
+		return
+	}
+
+	service, err := Factory.Core().V1().Services().Lister().Services(namespace).Get(name)
+	if err != nil {
+		// Endpoints can outlive their service for a moment when it is deleted.
+		return
+	}
+
+	prevReport := models.HealthReportForServiceEndpoints(*service, prev, Factory)
+	report := models.HealthReportForServiceEndpoints(*service, curr, Factory)
+	if report.Healthy != prevReport.Healthy {
+		update := models.HealthUpdate{
+			Action:          "update",
+			Kind:            "service",
+			Namespace:       report.Namespace,
+			Name:            report.Name,
+			Healthy:         report.Healthy,
+			PreviousHealthy: prevReport.Healthy,
+			Errors:          report.Errors,
+		}
+
+		HealthUpdates.BroadcastFilter(update.ToMsg(), filterService)
+		broadcastNamespaceHealth(report.Namespace)
+	}
+}
@@ -5,14 +5,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
-	veleroclient "github.com/vmware-tanzu/velero/pkg/client"
+	veleroclientset "github.com/vmware-tanzu/velero/pkg/generated/clientset/versioned"
+	"github.com/zanloy/bms-api/models"
 	"gopkg.in/olahol/melody.v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
 	ogkubernetes "k8s.io/client-go/kubernetes"
@@ -33,13 +36,20 @@ var (
 
 /* Package scoped variables */
 var (
-	logger        zerolog.Logger
-	Clientset     ogkubernetes.Interface
-	Config        *rest.Config
-	Factory       informers.SharedInformerFactory
-	HealthUpdates = melody.New()
-	stopCh        <-chan struct{}
-	tenants       = map[string][]string{} // Key is tenant name, value is envs
+	logger          zerolog.Logger
+	Clientset       ogkubernetes.Interface
+	VeleroClientset veleroclientset.Interface
+	Config          *rest.Config
+	Factory         informers.SharedInformerFactory
+	// BMSConfigFactory only watches the bms ConfigMaps so we don't cache
+	// every ConfigMap in the cluster.
+	BMSConfigFactory informers.SharedInformerFactory
+	HealthUpdates    = melody.New()
+	stopCh           <-chan struct{}
+	tenants          = map[string][]string{} // Key is tenant name, value is envs
+	// cronJobsServed is false when the cluster doesn't serve batch/v1
+	// CronJobs so there is no informer for them.
+	cronJobsServed bool
 )
 
 func FileExists(filename string) bool {
@@ -88,6 +98,11 @@ func Init(kubeconfig string) (err error) {
 		return err
 	}
 
+	VeleroClientset, err = veleroclientset.NewForConfig(Config)
+	if err != nil {
+		return err
+	}
+
 	logger.Debug().Msg("Kubernetes initilization complete.")
 	return nil
 }
@@ -96,6 +111,10 @@ func InitWithClientset(clientset ogkubernetes.Interface) {
 	Clientset = clientset
 }
 
+func InitWithVeleroClientset(clientset veleroclientset.Interface) {
+	VeleroClientset = clientset
+}
+
 func Start(stopChannel <-chan struct{}) {
 	logger.Info().Msg("Kubernetes controller startup initialized.")
 	stopCh = stopChannel
@@ -103,14 +122,29 @@ func Start(stopChannel <-chan struct{}) {
 	/* Setup cache and informers */
 	Factory = informers.NewSharedInformerFactory(Clientset, time.Minute*5)
 	setupInformers()
-	go Factory.Start(stopCh)
+	models.SetNamespaceLister(Factory.Core().V1().Namespaces().Lister())
+	BMSConfigFactory = informers.NewSharedInformerFactoryWithOptions(Clientset, time.Minute*5,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", models.BMSConfigMapName).String()
+		}))
+	models.SetBMSConfigLister(BMSConfigFactory.Core().V1().ConfigMaps().Lister())
+	setupBMSConfigInformer()
+	// Start is non-blocking. It must return before we wait on the caches or
+	// there is nothing for WaitForCacheSync to wait on.
+	Factory.Start(stopCh)
+	BMSConfigFactory.Start(stopCh)
 
 	// TODO: Add a timeout to this.
 	/* Wait for cache to sync */
 	logger.Info().Msg("Waiting for cache to sync...")
 	startTime := time.Now()
-	result := Factory.WaitForCacheSync(stopCh)
-	fmt.Printf("result = %+v\n", result)
+	for _, factory := range []informers.SharedInformerFactory{Factory, BMSConfigFactory} {
+		for informer, synced := range factory.WaitForCacheSync(stopCh) {
+			if !synced {
+				logger.Warn().Str("informer", informer.String()).Msg("Cache failed to sync.")
+			}
+		}
+	}
 	logger.Info().Msg(fmt.Sprintf("Cache sync completed [%.2fs].", time.Since(startTime).Seconds()))
 
 	logger.Info().Msg("Kubernetes controller startup complete.")
@@ -126,16 +160,11 @@ func mustBeInitialized() {
 
 func NamespaceExists(name string) bool {
 	ns, err := Namespaces().Get(name)
-	fmt.Println(name)
-	fmt.Printf("ns = %+v\n", ns)
-	fmt.Printf("err = %+v\n", err)
-	return err != nil && ns != nil
+	return err == nil && ns != nil
 }
 
 func NamespacesArray() (namespaces []string, err error) {
 	cached, err := Namespaces().List(labels.Everything())
-	fmt.Printf("cached = %+v\n", cached)
-	fmt.Printf("err = %+v\n", err)
 	if err != nil {
 		return
 	}
@@ -144,57 +173,38 @@ func NamespacesArray() (namespaces []string, err error) {
 	for idx, ns := range cached {
 		namespaces[idx] = ns.Name
 	}
+	sort.Strings(namespaces)
 	return
 }
 
-func VeleroBackups() (backups *velerov1.BackupList, err error) {
-	backups = &velerov1.BackupList{}
-
-	// Load Velero config (default: ~/.config/velero/config.json)
-	veleroConfig, err := veleroclient.LoadConfig()
-
-	// Use the Velero factory to build our velero objs.
-	// TODO: Analyze if this could be used for the entire package since it builds for k8 and velero
-	f := veleroclient.NewFactory("get", veleroConfig)
-
-	// Create veleroClient
-	veleroClient, err := f.Client()
+// apiServed returns true if the cluster serves resource in groupVersion.
+func apiServed(groupVersion string, resource string) bool {
+	resources, err := Clientset.Discovery().ServerResourcesForGroupVersion(groupVersion)
 	if err != nil {
-		return
+		logger.Debug().Err(err).Str("groupVersion", groupVersion).Msg("Failed to discover resources.")
+		return false
+	}
+
+	for _, served := range resources.APIResources {
+		if served.Name == resource {
+			return true
+		}
 	}
+	return false
+}
 
+// VeleroBackups returns the velero backups in namespace.
+func VeleroBackups(namespace string) (backups *velerov1.BackupList, err error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Get backups
-	backups, err = veleroClient.VeleroV1().Backups(f.Namespace()).List(ctx, metav1.ListOptions{})
-
-	// Return our results/error
-	return
+	return VeleroClientset.VeleroV1().Backups(namespace).List(ctx, metav1.ListOptions{})
 }
 
-func VeleroSchedules() (schedules *velerov1.ScheduleList, err error) {
-	schedules = &velerov1.ScheduleList{}
-
+// VeleroSchedules returns the velero schedules in namespace.
+func VeleroSchedules(namespace string) (schedules *velerov1.ScheduleList, err error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Load Velero config (default: ~/.config/velero/config.json)
-	veleroConfig, err := veleroclient.LoadConfig()
-
-	// Use the Velero factory to build our velero objs.
-	// TODO: Analyze if this could be used for the entire package since it builds for k8 and velero
-	f := veleroclient.NewFactory("get", veleroConfig)
-
-	// Create veleroClient
-	veleroClient, err := f.Client()
-	if err != nil {
-		return
-	}
-
-	// Get schedules
-	schedules, err = veleroClient.VeleroV1().Schedules(f.Namespace()).List(ctx, metav1.ListOptions{})
-
-	// Return our results/error
-	return
+	return VeleroClientset.VeleroV1().Schedules(namespace).List(ctx, metav1.ListOptions{})
 }
@@ -1,14 +1,15 @@
 package kubernetes_test
 
 import (
-	"context"
-	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/zanloy/bms-api/kubernetes"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
@@ -27,12 +28,6 @@ func startK8(stopCh <-chan struct{}) {
 		genNamespace("test3"),
 	)
 
-	if namespaces, err := kubernetes.Clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{}); err == nil {
-		fmt.Printf("namespaces = %+v\n", namespaces)
-	} else {
-		fmt.Printf("There was an error trying to get namespace list: %s\n", err.Error())
-	}
-
 	// Start kubernetes component
 	kubernetes.Start(stopCh)
 }
@@ -89,3 +84,45 @@ func TestNamespacesArray(t *testing.T) {
 		assert.Equal(t, testcase.expected, result, testcase.desc)
 	}
 }
+
+func TestCronJobs(t *testing.T) {
+	cases := []struct {
+		desc     string
+		served   bool
+		expected int
+	}{{
+		desc:     "where batch/v1 cronjobs are served",
+		served:   true,
+		expected: 1,
+	}, {
+		desc:     "where batch/v1 cronjobs aren't served",
+		served:   false,
+		expected: 0,
+	}}
+
+	for _, testcase := range cases {
+		stopCh := make(chan struct{})
+
+		clientset := fake.NewSimpleClientset(&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "test1", Name: "cleanup"}})
+		if testcase.served {
+			clientset.Fake.Resources = []*metav1.APIResourceList{{
+				GroupVersion: "batch/v1",
+				APIResources: []metav1.APIResource{{Name: "cronjobs", Namespaced: true, Kind: "CronJob"}},
+			}}
+		}
+		kubernetes.Clientset = clientset
+		kubernetes.Start(stopCh)
+
+		cronjobs, err := kubernetes.CronJobs("").List(labels.Everything())
+		assert.NoError(t, err, testcase.desc)
+		assert.Len(t, cronjobs, testcase.expected, testcase.desc)
+
+		// An informer added after Start is never started. Start the factory
+		// again so one would show up here.
+		kubernetes.Factory.Start(stopCh)
+		_, registered := kubernetes.Factory.WaitForCacheSync(stopCh)[reflect.TypeOf(&batchv1.CronJob{})]
+		assert.Equal(t, testcase.served, registered, testcase.desc)
+
+		close(stopCh)
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"context"
 	"os"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	controllers "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -21,8 +21,8 @@ func StartManager(ctx context.Context) {
 	mylogger.Info("kubernetes manager created", "manager", manager)
 
 	err = controllers.
-		NewControllerManagedBy(manager).      // Create the controller
-		For(&extensionsv1beta1.Deployment{}). // to watch Deployments
+		NewControllerManagedBy(manager). // Create the controller
+		For(&appsv1.Deployment{}).       // to watch Deployments
 		Complete(&DeploymentReconciler{Client: manager.GetClient()})
 	if err != nil {
 		mylogger.Error(err, "unable to create Deployments controller")
@@ -41,7 +41,7 @@ type DeploymentReconciler struct {
 
 /* Business Logic */
 func (dr *DeploymentReconciler) Reconcile(ctx context.Context, req controllers.Request) (controllers.Result, error) {
-	deployment := &extensionsv1beta1.Deployment{}
+	deployment := &appsv1.Deployment{}
 	err := dr.Get(ctx, req.NamespacedName, deployment)
 	if err != nil {
 		return controllers.Result{}, err
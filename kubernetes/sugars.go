@@ -5,10 +5,10 @@ package kubernetes // import github.com/zanloy/bms-api/kubernetes
 import (
 	informersappsv1 "k8s.io/client-go/informers/apps/v1"
 	informersv1 "k8s.io/client-go/informers/core/v1"
-	informersv1beta1 "k8s.io/client-go/informers/extensions/v1beta1"
 	listersappsv1 "k8s.io/client-go/listers/apps/v1"
+	listersbatchv1 "k8s.io/client-go/listers/batch/v1"
 	listersv1 "k8s.io/client-go/listers/core/v1"
-	listersv1beta1 "k8s.io/client-go/listers/extensions/v1beta1"
+	"k8s.io/client-go/tools/cache"
 )
 
 // Returns a base appsv1 interface.
@@ -23,22 +23,34 @@ func Core() informersv1.Interface {
 	return Factory.Core().V1()
 }
 
+// Returns a lister interface for cronjobs. It lists nothing if the cluster
+// doesn't serve batch/v1 CronJobs.
+func CronJobs(namespace string) listersbatchv1.CronJobNamespaceLister {
+	mustBeInitialized()
+	if !cronJobsServed {
+		// Asking the factory would add an informer that is never started.
+		empty := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		return listersbatchv1.NewCronJobLister(empty).CronJobs(namespace)
+	}
+	return Factory.Batch().V1().CronJobs().Lister().CronJobs(namespace)
+}
+
 // Returns a lister interface for daemonsets.
-func DaemonSets(namespace string) listersv1beta1.DaemonSetNamespaceLister {
+func DaemonSets(namespace string) listersappsv1.DaemonSetNamespaceLister {
 	mustBeInitialized()
-	return Extensions().DaemonSets().Lister().DaemonSets(namespace)
+	return Apps().DaemonSets().Lister().DaemonSets(namespace)
 }
 
 // Returns a lister interface for deployments.
-func Deployments(namespace string) listersv1beta1.DeploymentNamespaceLister {
+func Deployments(namespace string) listersappsv1.DeploymentNamespaceLister {
 	mustBeInitialized()
-	return Extensions().Deployments().Lister().Deployments(namespace)
+	return Apps().Deployments().Lister().Deployments(namespace)
 }
 
-// Returns a base extensions interface.
-func Extensions() informersv1beta1.Interface {
+// Returns a lister interface for jobs.
+func Jobs(namespace string) listersbatchv1.JobNamespaceLister {
 	mustBeInitialized()
-	return Factory.Extensions().V1beta1()
+	return Factory.Batch().V1().Jobs().Lister().Jobs(namespace)
 }
 
 // Return a lister interface for namespaces.
@@ -53,6 +65,18 @@ func Nodes() listersv1.NodeLister {
 	return Core().Nodes().Lister()
 }
 
+// Return a lister interface for persistentvolumes.
+func PersistentVolumes() listersv1.PersistentVolumeLister {
+	mustBeInitialized()
+	return Core().PersistentVolumes().Lister()
+}
+
+// Returns a lister interface for persistentvolumeclaims.
+func PersistentVolumeClaims(namespace string) listersv1.PersistentVolumeClaimNamespaceLister {
+	mustBeInitialized()
+	return Core().PersistentVolumeClaims().Lister().PersistentVolumeClaims(namespace)
+}
+
 // Returns a lister interface for pods.
 func Pods(namespace string) listersv1.PodNamespaceLister {
 	mustBeInitialized()
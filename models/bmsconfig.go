@@ -1,6 +1,153 @@
 package models
 
+import (
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// A namespace can describe itself to bms-api with a ConfigMap named
+// BMSConfigMapName holding a BMSConfig in its BMSConfigMapKey. Ex:
+//
+//	apiVersion: v1
+//	kind: ConfigMap
+//	metadata:
+//	  name: bms
+//	  namespace: app-dev
+//	data:
+//	  bms.yaml: |
+//	    tenant: app
+//	    environment: dev
+//	    contacts:
+//	      - name: App Team
+//	        email: app-team@example.com
+//	    runbooks:
+//	      - name: Restarting the api
+//	        url: https://wiki.example.com/app/runbooks/api
+//	    ignore_pods:
+//	      - selector: app=load-test
+//	    restart_threshold: 20
+//	    pods:
+//	      pending_threshold: 30m
+const (
+	BMSConfigMapName = "bms"
+	BMSConfigMapKey  = "bms.yaml"
+)
+
+// BMSConfig is the per-namespace config. Anything left unset falls back to
+// the bms-api config.
 type BMSConfig struct {
-	Tenant      string `json:"tenant"`
-	Environment string `json:"environment"`
+	Tenant           string       `json:"tenant,omitempty"`
+	Environment      string       `json:"environment,omitempty"`
+	Contacts         []Contact    `json:"contacts,omitempty"`
+	Runbooks         []Runbook    `json:"runbooks,omitempty"`
+	IgnorePods       []IgnoreRule `json:"ignore_pods,omitempty"` // In addition to the bms-api ignore_pods.
+	RestartThreshold int          `json:"restart_threshold,omitempty"`
+	Pods             PodsConfig   `json:"pods,omitempty"`
+	Jobs             JobsConfig   `json:"jobs,omitempty"`
+}
+
+// A Contact is who to reach out to about a namespace.
+type Contact struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Chat  string `json:"chat,omitempty"` // Ex: a slack channel
+}
+
+type Runbook struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// BMSConfigFromConfigMap parses the BMSConfig out of a namespace's bms
+// ConfigMap.
+func BMSConfigFromConfigMap(configmap corev1.ConfigMap) (BMSConfig, error) {
+	config := BMSConfig{}
+	data, ok := configmap.Data[BMSConfigMapKey]
+	if !ok {
+		return config, fmt.Errorf("The ConfigMap [%s/%s] does not have the key [%s].", configmap.Namespace, configmap.Name, BMSConfigMapKey)
+	}
+	if err := yaml.UnmarshalStrict([]byte(data), &config); err != nil {
+		return BMSConfig{}, fmt.Errorf("Failed to parse [%s] in the ConfigMap [%s/%s]: %w", BMSConfigMapKey, configmap.Namespace, configmap.Name, err)
+	}
+	return config, nil
+}
+
+// namespaceSettings is a namespace's parsed BMSConfig. It is cached until the
+// ConfigMap's resource version changes.
+type namespaceSettings struct {
+	resourceVersion string
+	config          BMSConfig
+	ignoreRules     []ignoreRule
+	err             error
+}
+
+var (
+	configMapLister        listerscorev1.ConfigMapLister
+	namespaceSettingsCache = map[string]namespaceSettings{}
+	namespaceSettingsMu    sync.RWMutex
+)
+
+// SetBMSConfigLister gives the models a way to look up the bms ConfigMap of a
+// namespace.
+func SetBMSConfigLister(lister listerscorev1.ConfigMapLister) {
+	namespaceSettingsMu.Lock()
+	defer namespaceSettingsMu.Unlock()
+	configMapLister = lister
+	namespaceSettingsCache = map[string]namespaceSettings{}
+}
+
+// BMSConfigFor returns the BMSConfig of the namespace. It is empty if the
+// namespace doesn't have a bms ConfigMap or it can't be parsed.
+func BMSConfigFor(namespace string) (BMSConfig, error) {
+	settings := namespaceSettingsFor(namespace)
+	return settings.config, settings.err
+}
+
+func namespaceSettingsFor(namespace string) namespaceSettings {
+	// This runs for every object we check so only the cache fill takes the
+	// write lock.
+	namespaceSettingsMu.RLock()
+	lister := configMapLister
+	cached, isCached := namespaceSettingsCache[namespace]
+	namespaceSettingsMu.RUnlock()
+
+	if lister == nil {
+		return namespaceSettings{}
+	}
+
+	configmap, err := lister.ConfigMaps(namespace).Get(BMSConfigMapName)
+	if err != nil {
+		if isCached {
+			namespaceSettingsMu.Lock()
+			delete(namespaceSettingsCache, namespace)
+			namespaceSettingsMu.Unlock()
+		}
+		if k8errors.IsNotFound(err) {
+			return namespaceSettings{}
+		}
+		return namespaceSettings{err: fmt.Errorf("Failed to get the ConfigMap [%s/%s]: %w", namespace, BMSConfigMapName, err)}
+	}
+
+	if isCached && cached.resourceVersion == configmap.ResourceVersion {
+		return cached
+	}
+
+	settings := namespaceSettings{resourceVersion: configmap.ResourceVersion}
+	if settings.config, settings.err = BMSConfigFromConfigMap(*configmap); settings.err == nil {
+		settings.ignoreRules, settings.err = compileIgnoreRules(settings.config.IgnorePods)
+	}
+	if settings.err == nil {
+		settings.err = validatePodsConfig(settings.config.Pods)
+	}
+
+	namespaceSettingsMu.Lock()
+	namespaceSettingsCache[namespace] = settings
+	namespaceSettingsMu.Unlock()
+
+	return settings
 }
@@ -0,0 +1,126 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func genBMSConfigMap(namespace string, data string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: BMSConfigMapName, ResourceVersion: "1"},
+		Data:       map[string]string{BMSConfigMapKey: data},
+	}
+}
+
+func TestBMSConfigFromConfigMap(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		configmap *corev1.ConfigMap
+		expected  BMSConfig
+		err       bool
+	}{{
+		desc: "with a full config",
+		configmap: genBMSConfigMap("app-dev", `
+tenant: app
+environment: dev
+contacts:
+  - name: App Team
+    email: app-team@example.com
+    chat: "#app-team"
+runbooks:
+  - name: Restarting the api
+    url: https://wiki.example.com/app/runbooks/api
+ignore_pods:
+  - selector: app=load-test
+restart_threshold: 20
+pods:
+  pending_threshold: 30m
+`),
+		expected: BMSConfig{
+			Tenant:           "app",
+			Environment:      "dev",
+			Contacts:         []Contact{{Name: "App Team", Email: "app-team@example.com", Chat: "#app-team"}},
+			Runbooks:         []Runbook{{Name: "Restarting the api", URL: "https://wiki.example.com/app/runbooks/api"}},
+			IgnorePods:       []IgnoreRule{{Selector: "app=load-test"}},
+			RestartThreshold: 20,
+			Pods:             PodsConfig{PendingThreshold: "30m"},
+		},
+	}, {
+		desc:      "with an unknown key",
+		configmap: genBMSConfigMap("app-dev", "tennant: app\n"),
+		err:       true,
+	}, {
+		desc:      "without the bms.yaml key",
+		configmap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: BMSConfigMapName}},
+		err:       true,
+	}}
+
+	for _, tc := range testCases {
+		result, err := BMSConfigFromConfigMap(*tc.configmap)
+		if tc.err {
+			assert.Error(t, err, tc.desc)
+		} else {
+			assert.NoError(t, err, tc.desc)
+			assert.Equal(t, tc.expected, result, tc.desc)
+		}
+	}
+}
+
+func TestBMSConfigFor(t *testing.T) {
+	defer SetBMSConfigLister(nil)
+
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	configmaps := factory.Core().V1().ConfigMaps().Informer().GetIndexer()
+	configmaps.Add(genBMSConfigMap("legacy", `
+tenant: app
+environment: prod
+contacts:
+  - name: App Team
+ignore_pods:
+  - selector: app=load-test
+pods:
+  pending_threshold: 1h
+`))
+	configmaps.Add(genBMSConfigMap("broken-dev", "tenant: [app\n"))
+	configmaps.Add(genBMSConfigMap("typo-dev", "pods:\n  pending_threshold: 1 hour\n"))
+	SetBMSConfigLister(factory.Core().V1().ConfigMaps().Lister())
+
+	// Tenant, environment and contacts
+	namespace := FromK8Namespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy"}}, factory)
+	assert.Equal(t, "app", namespace.Tenant)
+	assert.Equal(t, "prod", namespace.Env)
+	assert.Equal(t, []Contact{{Name: "App Team"}}, namespace.Contacts)
+
+	// A broken ConfigMap shows up in the namespace's errors.
+	namespace = FromK8Namespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "broken-dev"}}, factory)
+	assert.Equal(t, "broken", namespace.Tenant, "the name is still used")
+	assert.Len(t, namespace.Errors, 1)
+
+	// So does a threshold that can't be parsed.
+	namespace = FromK8Namespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "typo-dev"}}, factory)
+	if assert.Len(t, namespace.Errors, 1) {
+		assert.Contains(t, namespace.Errors[0], "pods.pending_threshold")
+	}
+
+	// Ignore rules only apply to their namespace.
+	loadTest := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "legacy", Name: "load-test-1", Labels: map[string]string{"app": "load-test"}}}
+	assert.True(t, PodIsIgnored(loadTest))
+	loadTest.Namespace = "app-dev"
+	assert.False(t, PodIsIgnored(loadTest))
+
+	// Thresholds
+	pending := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "legacy", Name: "api-1", CreationTimestamp: metav1.NewTime(time.Now().Add(-30 * time.Minute))},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	assert.Equal(t, StatusWarn, FromK8Pod(pending).Healthy)
+	pending.Namespace = "app-dev"
+	assert.Equal(t, StatusUnhealthy, FromK8Pod(pending).Healthy)
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"fmt"
+	"time"
+)
+
 type FilterAction string
 
 const (
@@ -18,8 +23,111 @@ type Filter struct {
 // This is the structure of our bms-api config file and will be used to
 // marshal our config file.
 type Config struct {
-	Namespace  string     `json:"namespace"`
-	MaxReports int        `json:"max_reports,omitempty"`
-	Filters    []Filter   `json:"filters,omitempty"`
-	Urls       []URLCheck `json:"urls,omitempty"`
+	Namespace  string `json:"namespace"`
+	MaxReports int    `json:"max_reports,omitempty"`
+	// Retention tiers for stored reports. When set, max_reports only applies
+	// if it is set explicitly.
+	Retention   []RetentionTier `json:"retention,omitempty"`
+	SaveReports bool            `json:"save_reports,omitempty"` // Default for /reports/create when ?save= is not given.
+	// RestartThreshold is how many times a container may restart before it is
+	// listed in the restarts section of reports. Default: 5
+	RestartThreshold int `json:"restart_threshold,omitempty"`
+	// ReportSchedule is a cron expression (ex: "0 * * * *") for bms-api to
+	// generate and save reports on its own.
+	ReportSchedule string     `json:"report_schedule,omitempty"`
+	Pods           PodsConfig `json:"pods,omitempty"`
+	Jobs           JobsConfig `json:"jobs,omitempty"`
+	// IgnorePods are rules for pods to leave out of health checks in addition
+	// to DefaultIgnoreRules, unless IgnorePodsReplaceDefaults is set.
+	IgnorePods                []IgnoreRule  `json:"ignore_pods,omitempty"`
+	IgnorePodsReplaceDefaults bool          `json:"ignore_pods_replace_defaults,omitempty"`
+	Tenancy                   TenancyConfig `json:"tenancy,omitempty"`
+	Storage                   StorageConfig `json:"storage,omitempty"`
+	Velero                    VeleroConfig  `json:"velero,omitempty"`
+	Filters                   []Filter      `json:"filters,omitempty"`
+	Urls                      []URLCheck    `json:"urls,omitempty"`
+}
+
+// JobsConfig is how overdue a CronJob may be before it is unhealthy.
+type JobsConfig struct {
+	// CronJobMultiple is how many times the time between runs of a CronJob's
+	// schedule may pass since its last successful run. Default: 2
+	CronJobMultiple float64 `json:"cronjob_multiple,omitempty"`
+}
+
+// PodsConfig is how long a pod may be stuck before it is unhealthy. Until then
+// it is Warn. Durations accept d and w for days and weeks.
+type PodsConfig struct {
+	PendingThreshold     string `json:"pending_threshold,omitempty"`     // Default: 10m
+	TerminatingThreshold string `json:"terminating_threshold,omitempty"` // Default: 20m
+}
+
+// A RetentionTier keeps stored reports younger than Within. If Every is set
+// only one report per Every is kept. Durations accept d and w for days and
+// weeks. Ex: keep everything for 48h, one per day for 30d, one per week for
+// 52w:
+//
+//	retention:
+//	  - within: 48h
+//	  - within: 30d
+//	    every: 1d
+//	  - within: 52w
+//	    every: 1w
+type RetentionTier struct {
+	Within string `json:"within"`
+	Every  string `json:"every,omitempty"`
+}
+
+// Durations parses Within and Every. Every is zero if it isn't set.
+func (tier RetentionTier) Durations() (within time.Duration, every time.Duration, err error) {
+	within, err = ParseDuration(tier.Within)
+	if err != nil || within <= 0 {
+		return 0, 0, fmt.Errorf("Invalid retention within [%s]: must be a positive duration like 48h, 30d, or 52w.", tier.Within)
+	}
+
+	if tier.Every != "" {
+		every, err = ParseDuration(tier.Every)
+		if err != nil || every < 0 {
+			return 0, 0, fmt.Errorf("Invalid retention every [%s]: must be a duration like 1h, 1d, or 1w.", tier.Every)
+		}
+	}
+
+	return within, every, nil
+}
+
+// StorageConfig picks where reports are stored.
+type StorageConfig struct {
+	// Backend is one of secret (default), configmap, filesystem, or s3.
+	Backend string `json:"backend,omitempty"`
+	// Name of the Secret or ConfigMap in our namespace. Default: bms-reports
+	Name string `json:"name,omitempty"`
+	// MaxChunks is the most Secrets or ConfigMaps (of ~900KiB each) that the
+	// reports may be split across. Default: 10
+	MaxChunks int `json:"max_chunks,omitempty"`
+	// Path is the directory used by the filesystem backend.
+	Path string   `json:"path,omitempty"`
+	S3   S3Config `json:"s3,omitempty"`
+}
+
+// S3Config is the connection info for an S3 compatible object store. If
+// AccessKeyID is empty the standard AWS_* env vars are used. Saves to S3 are
+// not fully safe with more than one writer, so only one replica should save
+// reports to it.
+type S3Config struct {
+	Endpoint        string `json:"endpoint"` // host[:port] without a scheme
+	Bucket          string `json:"bucket"`
+	Key             string `json:"key,omitempty"` // Default: bms-reports.json.gz
+	Region          string `json:"region,omitempty"`
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+	Insecure        bool   `json:"insecure,omitempty"` // Use http instead of https.
+}
+
+// VeleroConfig is where to find velero and how fresh its backups must be.
+type VeleroConfig struct {
+	Namespace string `json:"namespace,omitempty"` // Default: velero
+	// MaxBackupAge is how long ago a schedule's last successful backup may
+	// have finished before the schedule is unhealthy. Accepts d and w for days
+	// and weeks. Default: 25h
+	MaxBackupAge string `json:"max_backup_age,omitempty"`
 }
@@ -0,0 +1,59 @@
+package models
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/client-go/informers"
+)
+
+// cronJobsServed is false when the cluster doesn't serve batch/v1 CronJobs.
+// Their informer is never started then so we must not ask for it.
+var cronJobsServed = true
+
+// SetCronJobsServed tells the models whether the cluster serves batch/v1
+// CronJobs.
+func SetCronJobsServed(served bool) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	cronJobsServed = served
+}
+
+func currentCronJobsServed() bool {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return cronJobsServed
+}
+
+type CronJob struct {
+	Name        string        `json:"name"`
+	Namespace   string        `json:"namespace"`
+	Tenant      string        `json:"tenant,omitempty"`
+	Environment string        `json:"environment,omitempty"`
+	Schedule    string        `json:"schedule"`
+	Suspended   bool          `json:"suspended,omitempty"`
+	Healthy     HealthyStatus `json:"healthy"`
+	Errors      []string      `json:"errors,omitempty"`
+}
+
+func FromK8CronJob(cronjob batchv1.CronJob, factory informers.SharedInformerFactory) CronJob {
+	var (
+		report              HealthReport
+		tenant, environment string
+	)
+
+	// Get tenant info
+	tenant, environment = parseTenantAndEnv(cronjob.Namespace)
+
+	// Get health report
+	report = HealthReportForCronJob(cronjob, factory)
+
+	return CronJob{
+		Name:        cronjob.Name,
+		Namespace:   cronjob.Namespace,
+		Tenant:      tenant,
+		Environment: environment,
+		Schedule:    cronjob.Spec.Schedule,
+		Suspended:   cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend,
+		Healthy:     report.Healthy,
+		Errors:      report.Errors,
+	}
+}
@@ -1,7 +1,7 @@
 package models
 
 import (
-	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
 )
 
 type DaemonSet struct {
@@ -13,7 +13,7 @@ type DaemonSet struct {
 	Errors      []string      `json:"errors,omitempty"`
 }
 
-func FromK8DaemonSet(daemonset extensionsv1beta1.DaemonSet) DaemonSet {
+func FromK8DaemonSet(daemonset appsv1.DaemonSet) DaemonSet {
 	var (
 		report              HealthReport
 		tenant, environment string
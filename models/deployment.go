@@ -1,7 +1,7 @@
 package models
 
 import (
-	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
 )
 
 type Deployment struct {
@@ -13,7 +13,7 @@ type Deployment struct {
 	Errors      []string      `json:"errors,omitempty"`
 }
 
-func FromK8Deployment(deployment extensionsv1beta1.Deployment) Deployment {
+func FromK8Deployment(deployment appsv1.Deployment) Deployment {
 	var (
 		report              HealthReport
 		tenant, environment string
@@ -0,0 +1,90 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFromK8Deployment(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		replicas   int32
+		ready      int32
+		conditions []appsv1.DeploymentCondition
+		healthy    HealthyStatus
+		errors     []string
+	}{{
+		desc:     "with an available deployment",
+		replicas: 3,
+		ready:    3,
+		conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue},
+		},
+		healthy: StatusHealthy,
+	}, {
+		desc:     "with a rolling restart",
+		replicas: 3,
+		ready:    1,
+		conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse, Message: "Deployment does not have minimum availability."},
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue},
+		},
+		healthy: StatusWarn,
+		errors: []string{
+			"The number of desired replicas [3] does not match the number of ready replicas [1].",
+			"Deployment does not have minimum availability.",
+		},
+	}, {
+		desc:     "with no ready replicas",
+		replicas: 3,
+		conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse, Message: "Deployment does not have minimum availability."},
+		},
+		healthy: StatusUnhealthy,
+		errors: []string{
+			"None of the desired replicas [3] are ready.",
+			"Deployment does not have minimum availability.",
+		},
+	}, {
+		desc:    "scaled to zero",
+		healthy: StatusHealthy,
+	}, {
+		desc:     "with a stuck rollout",
+		replicas: 1,
+		ready:    1,
+		conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded", Message: `ReplicaSet "api-5d8f" has timed out progressing.`},
+		},
+		healthy: StatusUnhealthy,
+		errors:  []string{`ReplicaSet "api-5d8f" has timed out progressing.`},
+	}, {
+		desc:     "with a replica failure",
+		replicas: 2,
+		ready:    2,
+		conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentReplicaFailure, Status: corev1.ConditionTrue, Message: `pods "api-5d8f-" is forbidden: exceeded quota`},
+		},
+		healthy: StatusUnhealthy,
+		errors:  []string{`pods "api-5d8f-" is forbidden: exceeded quota`},
+	}}
+
+	for _, tc := range testCases {
+		deployment := appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: "api"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &tc.replicas},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: tc.ready, Conditions: tc.conditions},
+		}
+		result := FromK8Deployment(deployment)
+		assert.Equal(t, tc.healthy, result.Healthy, tc.desc)
+		assert.Equal(t, tc.errors, result.Errors, tc.desc)
+		assert.Equal(t, "app", result.Tenant, tc.desc)
+		assert.Equal(t, "dev", result.Environment, tc.desc)
+	}
+}
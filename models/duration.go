@@ -0,0 +1,37 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// The leading dot is captured so fractions like 1.5d can be rejected instead
+// of being rewritten into something else.
+var dayWeekUnits = regexp.MustCompile(`(\.?)(\d+)([dw])`)
+
+// ParseDuration is time.ParseDuration with support for days (d) and weeks (w)
+// since nobody wants to write 720h for 30 days. Days and weeks must be whole
+// numbers.
+func ParseDuration(input string) (time.Duration, error) {
+	var err error
+	expanded := dayWeekUnits.ReplaceAllStringFunc(input, func(match string) string {
+		parts := dayWeekUnits.FindStringSubmatch(match)
+		if parts[1] != "" {
+			err = fmt.Errorf("Invalid duration %q: days and weeks must be whole numbers.", input)
+			return match
+		}
+		count, _ := strconv.Atoi(parts[2])
+		hours := count * 24
+		if parts[3] == "w" {
+			hours *= 7
+		}
+		return fmt.Sprintf("%dh", hours)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return time.ParseDuration(expanded)
+}
@@ -0,0 +1,35 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected time.Duration
+		err      bool
+	}{
+		{input: "48h", expected: 48 * time.Hour},
+		{input: "30d", expected: 30 * 24 * time.Hour},
+		{input: "52w", expected: 52 * 7 * 24 * time.Hour},
+		{input: "1w2d12h", expected: (9*24 + 12) * time.Hour},
+		{input: "forever", err: true},
+		{input: "1.5d", err: true},
+		{input: "12.25w", err: true},
+	}
+
+	for _, testcase := range cases {
+		result, err := ParseDuration(testcase.input)
+		if testcase.err {
+			assert.Error(t, err, testcase.input)
+		} else {
+			assert.NoError(t, err, testcase.input)
+			assert.Equal(t, testcase.expected, result, testcase.input)
+		}
+	}
+}
@@ -2,13 +2,17 @@ package models
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/informers"
 )
 
@@ -43,16 +47,26 @@ func NewHealthReport() HealthReport {
 
 func HealthReportFor(obj interface{}, factory informers.SharedInformerFactory) (HealthReport, error) {
 	switch typed := obj.(type) {
-	case *extensionsv1beta1.DaemonSet:
+	case *batchv1.CronJob:
+		return HealthReportForCronJob(*typed, factory), nil
+	case *appsv1.DaemonSet:
 		return HealthReportForDaemonSet(*typed), nil
-	case *extensionsv1beta1.Deployment:
+	case *appsv1.Deployment:
 		return HealthReportForDeployment(*typed), nil
+	case *batchv1.Job:
+		return HealthReportForJob(*typed, factory), nil
 	case *corev1.Namespace:
 		return HealthReportForNamespace(*typed, factory), nil
 	case *corev1.Node:
 		return HealthReportForNode(*typed), nil
+	case *corev1.PersistentVolume:
+		return HealthReportForPersistentVolume(*typed), nil
+	case *corev1.PersistentVolumeClaim:
+		return HealthReportForPersistentVolumeClaim(*typed, factory), nil
 	case *corev1.Pod:
 		return HealthReportForPod(*typed), nil
+	case *corev1.Service:
+		return HealthReportForService(*typed, factory), nil
 	case *appsv1.StatefulSet:
 		return HealthReportForStatefulSet(*typed), nil
 	default:
@@ -60,17 +74,164 @@ func HealthReportFor(obj interface{}, factory informers.SharedInformerFactory) (
 	}
 }
 
-func HealthReportForDaemonSet(daemonset extensionsv1beta1.DaemonSet) HealthReport {
+// HealthReportForCronJob is Warn while the CronJob is suspended and Unhealthy
+// once its last successful run started longer ago than jobs.cronjob_multiple
+// times its schedule.
+func HealthReportForCronJob(cronjob batchv1.CronJob, f informers.SharedInformerFactory) HealthReport {
+	jobs, err := f.Batch().V1().Jobs().Lister().Jobs(cronjob.Namespace).List(labels.Everything())
+	if err != nil {
+		report := healthReportForCronJob(cronjob, nil, time.Now())
+		report.Healthy = StatusUnknown
+		report.Errors = append(report.Errors, "Failed to fetch Jobs from Kubernetes.")
+		return report
+	}
+
+	return healthReportForCronJob(cronjob, jobs, time.Now())
+}
+
+func healthReportForCronJob(cronjob batchv1.CronJob, jobs []*batchv1.Job, now time.Time) HealthReport {
+	report := NewHealthReport()
+	report.Kind = "CronJob"
+	report.Namespace = cronjob.Namespace
+	report.Name = cronjob.Name
+	report.Tenant, report.Environment = parseTenantAndEnv(cronjob.Namespace)
+
+	if cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend {
+		// A suspended CronJob isn't expected to run so how long ago it last
+		// did doesn't matter.
+		report.Healthy = StatusWarn
+		report.Errors = append(report.Errors, "The CronJob is suspended.")
+		return report
+	}
+
+	schedule, err := cron.ParseStandard(cronjob.Spec.Schedule)
+	if err != nil {
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, fmt.Sprintf("Failed to parse the schedule [%s]: %s", cronjob.Spec.Schedule, err))
+		return report
+	}
+
+	// Without any successful Jobs kept around we can't tell when it last
+	// succeeded.
+	if limit := cronjob.Spec.SuccessfulJobsHistoryLimit; limit == nil || *limit > 0 {
+		lastSuccess, found := lastSuccessfulJob(cronjob, jobs)
+		since := lastSuccess
+		if !found {
+			// Give a new CronJob the same time to succeed as an old one.
+			since = cronjob.CreationTimestamp.Time
+		}
+
+		maxAge := time.Duration(cronJobMultiple(cronjob.Namespace) * float64(longestGap(schedule, since)))
+		if age := now.Sub(since); age > maxAge {
+			report.Healthy = StatusUnhealthy
+			if found {
+				report.Errors = append(report.Errors, fmt.Sprintf("The last successful run of the CronJob started %s ago.", formatAge(age)))
+			} else {
+				report.Errors = append(report.Errors, fmt.Sprintf("The CronJob has not had a successful run in the %s since it was created.", formatAge(age)))
+			}
+		}
+	}
+
+	if report.Healthy == StatusUnknown {
+		report.Healthy = StatusHealthy
+	}
+
+	return report
+}
+
+// lastSuccessfulJob returns when the most recent Job of the CronJob that
+// completed successfully started.
+func lastSuccessfulJob(cronjob batchv1.CronJob, jobs []*batchv1.Job) (time.Time, bool) {
+	var (
+		last  time.Time
+		found bool
+	)
+
+	for _, job := range jobs {
+		if !ownedByCronJob(*job, cronjob.Name, cronjob.UID) || !jobCompleted(*job) {
+			continue
+		}
+		if started := jobStarted(*job); !found || started.After(last) {
+			last, found = started, true
+		}
+	}
+
+	return last, found
+}
+
+// ownedByCronJob returns true if the job was created by the named CronJob. An
+// empty uid matches any CronJob with that name.
+func ownedByCronJob(job batchv1.Job, name string, uid types.UID) bool {
+	for _, owner := range job.OwnerReferences {
+		if owner.Kind == "CronJob" && owner.Name == name && (uid == "" || owner.UID == "" || owner.UID == uid) {
+			return true
+		}
+	}
+	return false
+}
+
+func jobCompleted(job batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobComplete && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func jobStarted(job batchv1.Job) time.Time {
+	if job.Status.StartTime != nil {
+		return job.Status.StartTime.Time
+	}
+	return job.CreationTimestamp.Time
+}
+
+// jobSuperseded returns true if a newer run of the job's CronJob completed.
+// CronJobs keep their last failed Job around so without this one bad run
+// would stay unhealthy until the Job is cleaned up.
+func jobSuperseded(job batchv1.Job, f informers.SharedInformerFactory) bool {
+	for _, owner := range job.OwnerReferences {
+		if owner.Kind != "CronJob" {
+			continue
+		}
+
+		jobs, err := f.Batch().V1().Jobs().Lister().Jobs(job.Namespace).List(labels.Everything())
+		if err != nil {
+			return false
+		}
+		for _, sibling := range jobs {
+			if ownedByCronJob(*sibling, owner.Name, owner.UID) && jobCompleted(*sibling) && jobStarted(*sibling).After(jobStarted(job)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// longestGap is the longest time between the next several runs of the
+// schedule after since. Using the longest gap keeps schedules that skip days
+// (ex: weekdays only) from being unhealthy after the days they skip.
+func longestGap(schedule cron.Schedule, since time.Time) time.Duration {
+	var longest time.Duration
+	prev := schedule.Next(since)
+	for i := 0; i < 7; i++ {
+		next := schedule.Next(prev)
+		if gap := next.Sub(prev); gap > longest {
+			longest = gap
+		}
+		prev = next
+	}
+	return longest
+}
+
+func HealthReportForDaemonSet(daemonset appsv1.DaemonSet) HealthReport {
 	report := NewHealthReport()
-	report.Kind = daemonset.Kind
+	report.Kind = "DaemonSet"
 	report.Namespace = daemonset.Namespace
 	report.Name = daemonset.Name
 	report.Tenant, report.Environment = parseTenantAndEnv(daemonset.Namespace)
 
-	if daemonset.Status.DesiredNumberScheduled != daemonset.Status.NumberReady {
-		report.Healthy = StatusUnhealthy
-		report.Errors = append(report.Errors, fmt.Sprintf("The number of desired pods [%d] does not match the number of ready pods [%d].", daemonset.Status.DesiredNumberScheduled, daemonset.Status.NumberReady))
-	}
+	checkReadyReplicas(&report, "pods", daemonset.Status.DesiredNumberScheduled, daemonset.Status.NumberReady)
 
 	if report.Healthy == StatusUnknown {
 		report.Healthy = StatusHealthy
@@ -79,16 +240,31 @@ func HealthReportForDaemonSet(daemonset extensionsv1beta1.DaemonSet) HealthRepor
 	return report
 }
 
-func HealthReportForDeployment(deployment extensionsv1beta1.Deployment) HealthReport {
+// HealthReportForDeployment is Warn while some but not all replicas are ready
+// (ex: during a rolling restart) and Unhealthy when none are or the rollout is
+// stuck.
+func HealthReportForDeployment(deployment appsv1.Deployment) HealthReport {
 	report := NewHealthReport()
-	report.Kind = deployment.Kind
+	report.Kind = "Deployment"
 	report.Namespace = deployment.Namespace
 	report.Name = deployment.Name
 	report.Tenant, report.Environment = parseTenantAndEnv(deployment.Namespace)
 
+	checkReadyReplicas(&report, "replicas", desiredReplicas(deployment.Spec.Replicas), deployment.Status.ReadyReplicas)
+
 	for _, condition := range deployment.Status.Conditions {
-		if condition.Type == extensionsv1beta1.DeploymentAvailable && condition.Status == corev1.ConditionFalse {
-			report.Healthy = StatusUnhealthy
+		switch {
+		case condition.Type == appsv1.DeploymentAvailable && condition.Status == corev1.ConditionFalse:
+			// Below minimum availability is expected while replicas restart so
+			// how bad it is was decided by the ready replicas above.
+			escalate(&report, StatusWarn)
+			report.Errors = append(report.Errors, condition.Message)
+		case condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse:
+			// Usually a ProgressDeadlineExceeded from a rollout that is stuck.
+			escalate(&report, StatusUnhealthy)
+			report.Errors = append(report.Errors, condition.Message)
+		case condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == corev1.ConditionTrue:
+			escalate(&report, StatusUnhealthy)
 			report.Errors = append(report.Errors, condition.Message)
 		}
 	}
@@ -100,93 +276,127 @@ func HealthReportForDeployment(deployment extensionsv1beta1.Deployment) HealthRe
 	return report
 }
 
+// HealthReportForJob is Unhealthy once the Job has failed and Warn while it
+// is retrying failed pods. Failed runs of a CronJob are ignored once a newer
+// run has completed.
+func HealthReportForJob(job batchv1.Job, f informers.SharedInformerFactory) HealthReport {
+	report := NewHealthReport()
+	report.Kind = "Job"
+	report.Namespace = job.Namespace
+	report.Name = job.Name
+	report.Tenant, report.Environment = parseTenantAndEnv(job.Namespace)
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue && !jobSuperseded(job, f) {
+			report.Healthy = StatusUnhealthy
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", condition.Reason, condition.Message))
+		}
+	}
+
+	if report.Healthy == StatusUnknown && job.Status.Active > 0 && job.Status.Failed > 0 {
+		report.Healthy = StatusWarn
+		report.Errors = append(report.Errors, fmt.Sprintf("The Job is retrying after %d failed pods.", job.Status.Failed))
+	}
+
+	if report.Healthy == StatusUnknown {
+		report.Healthy = StatusHealthy
+	}
+
+	return report
+}
+
 func HealthReportForNamespace(namespace corev1.Namespace, f informers.SharedInformerFactory) HealthReport {
 	nsreport := NewHealthReport()
-	nsreport.Kind = namespace.Kind
+	nsreport.Kind = "Namespace"
 	nsreport.Name = namespace.Name
 	nsreport.Tenant, nsreport.Environment = parseTenantAndEnv(namespace.Name)
 
-	// Check DaemonSets
-	if daemonsets, err := f.Extensions().V1beta1().DaemonSets().Lister().DaemonSets(namespace.Name).List(labels.Everything()); err == nil {
-		unhealthyDaemonSets := make([]string, 0, len(daemonsets))
-		for _, daemonset := range daemonsets {
-			report := HealthReportForDaemonSet(*daemonset)
-			if report.Healthy != StatusHealthy {
-				unhealthyDaemonSets = append(unhealthyDaemonSets, daemonset.Name)
-			}
+	// Check CronJobs
+	if !currentCronJobsServed() {
+		// Nothing to check.
+	} else if cronjobs, err := f.Batch().V1().CronJobs().Lister().CronJobs(namespace.Name).List(labels.Everything()); err == nil {
+		reports := make([]HealthReport, len(cronjobs))
+		for idx, cronjob := range cronjobs {
+			reports[idx] = HealthReportForCronJob(*cronjob, f)
 		}
-		if len(unhealthyDaemonSets) > 0 {
-			nsreport.Healthy = StatusUnhealthy
-			nsreport.Errors = append(nsreport.Errors, fmt.Sprintf("DaemonSets with unhealthy status: [%s].", strings.Join(unhealthyDaemonSets, ",")))
+		rollUp(&nsreport, "CronJobs", reports)
+	} else {
+		nsreport.Errors = append(nsreport.Errors, "Failed to fetch CronJobs from Kubernetes.")
+	}
+
+	// Check DaemonSets
+	if daemonsets, err := f.Apps().V1().DaemonSets().Lister().DaemonSets(namespace.Name).List(labels.Everything()); err == nil {
+		reports := make([]HealthReport, len(daemonsets))
+		for idx, daemonset := range daemonsets {
+			reports[idx] = HealthReportForDaemonSet(*daemonset)
 		}
+		rollUp(&nsreport, "DaemonSets", reports)
 	} else {
 		nsreport.Errors = append(nsreport.Errors, "Failed to fetch DaemonSets from Kubernetes.")
 	}
 
 	// Check Deployments
-	if deployments, err := f.Extensions().V1beta1().Deployments().Lister().Deployments(namespace.Name).List(labels.Everything()); err == nil {
-		unhealthyDeployments := make([]string, 0, len(deployments))
-		for _, deployment := range deployments {
-			report := HealthReportForDeployment(*deployment)
-			if report.Healthy != StatusHealthy {
-				unhealthyDeployments = append(unhealthyDeployments, deployment.Name)
-			}
-		}
-		if len(unhealthyDeployments) > 0 {
-			nsreport.Healthy = StatusUnhealthy
-			nsreport.Errors = append(nsreport.Errors, fmt.Sprintf("Deployments with unhealthy status: [%s].", strings.Join(unhealthyDeployments, ",")))
+	if deployments, err := f.Apps().V1().Deployments().Lister().Deployments(namespace.Name).List(labels.Everything()); err == nil {
+		reports := make([]HealthReport, len(deployments))
+		for idx, deployment := range deployments {
+			reports[idx] = HealthReportForDeployment(*deployment)
 		}
+		rollUp(&nsreport, "Deployments", reports)
 	} else {
 		nsreport.Errors = append(nsreport.Errors, "Failed to fetch Deployments from Kubernetes.")
 	}
 
+	// Check Jobs
+	if jobs, err := f.Batch().V1().Jobs().Lister().Jobs(namespace.Name).List(labels.Everything()); err == nil {
+		reports := make([]HealthReport, len(jobs))
+		for idx, job := range jobs {
+			reports[idx] = HealthReportForJob(*job, f)
+		}
+		rollUp(&nsreport, "Jobs", reports)
+	} else {
+		nsreport.Errors = append(nsreport.Errors, "Failed to fetch Jobs from Kubernetes.")
+	}
+
+	// Check PersistentVolumeClaims
+	if claims, err := f.Core().V1().PersistentVolumeClaims().Lister().PersistentVolumeClaims(namespace.Name).List(labels.Everything()); err == nil {
+		reports := make([]HealthReport, len(claims))
+		for idx, claim := range claims {
+			reports[idx] = HealthReportForPersistentVolumeClaim(*claim, f)
+		}
+		rollUp(&nsreport, "PersistentVolumeClaims", reports)
+	} else {
+		nsreport.Errors = append(nsreport.Errors, "Failed to fetch PersistentVolumeClaims from Kubernetes.")
+	}
+
 	// Check Pods
 	if pods, err := f.Core().V1().Pods().Lister().Pods(namespace.Name).List(labels.Everything()); err == nil {
-		unhealthyPods := make([]string, 0, len(pods))
-		for _, pod := range pods {
-			report := HealthReportForPod(*pod)
-			if report.Healthy != StatusHealthy {
-				unhealthyPods = append(unhealthyPods, pod.Name)
-			}
-		}
-		if len(unhealthyPods) > 0 {
-			nsreport.Healthy = StatusUnhealthy
-			nsreport.Errors = append(nsreport.Errors, fmt.Sprintf("Pods with unhealthy status: [%s].", strings.Join(unhealthyPods, ",")))
+		reports := make([]HealthReport, len(pods))
+		for idx, pod := range pods {
+			reports[idx] = HealthReportForPod(*pod)
 		}
+		rollUp(&nsreport, "Pods", reports)
 	} else {
 		nsreport.Errors = append(nsreport.Errors, "Failed to fetch Pods from Kubernetes.")
 	}
 
 	// Check Services
 	if services, err := f.Core().V1().Services().Lister().Services(namespace.Name).List(labels.Everything()); err == nil {
-		unhealthyServices := make([]string, 0, len(services))
-		for _, service := range services {
-			report := HealthReportForService(*service, f)
-			if report.Healthy != StatusHealthy {
-				unhealthyServices = append(unhealthyServices, service.Name)
-			}
-		}
-		if len(unhealthyServices) > 0 {
-			nsreport.Healthy = StatusUnhealthy
-			nsreport.Errors = append(nsreport.Errors, fmt.Sprintf("Services with unhealthy status: [%s].", strings.Join(unhealthyServices, ",")))
+		reports := make([]HealthReport, len(services))
+		for idx, service := range services {
+			reports[idx] = HealthReportForService(*service, f)
 		}
+		rollUp(&nsreport, "Services", reports)
 	} else {
 		nsreport.Errors = append(nsreport.Errors, "Failed to fetch Services from Kubernetes.")
 	}
 
 	// Check StatefulSets
 	if statefulsets, err := f.Apps().V1().StatefulSets().Lister().StatefulSets(namespace.Name).List(labels.Everything()); err == nil {
-		unhealthyStatefulSets := make([]string, 0, len(statefulsets))
-		for _, statefulset := range statefulsets {
-			report := HealthReportForStatefulSet(*statefulset)
-			if report.Healthy != StatusHealthy {
-				unhealthyStatefulSets = append(unhealthyStatefulSets, statefulset.Name)
-			}
-		}
-		if len(unhealthyStatefulSets) > 0 {
-			nsreport.Healthy = StatusUnhealthy
-			nsreport.Errors = append(nsreport.Errors, fmt.Sprintf("StatefulSets with unhealthy status: [%s].", strings.Join(unhealthyStatefulSets, ",")))
+		reports := make([]HealthReport, len(statefulsets))
+		for idx, statefulset := range statefulsets {
+			reports[idx] = HealthReportForStatefulSet(*statefulset)
 		}
+		rollUp(&nsreport, "StatefulSets", reports)
 	} else {
 		nsreport.Errors = append(nsreport.Errors, "Failed to fetch StatefulSets from Kubernetes.")
 	}
@@ -226,6 +436,97 @@ func HealthReportForNode(node corev1.Node) HealthReport {
 	return report
 }
 
+// HealthReportForPersistentVolume is Warn while the volume is waiting to be
+// bound or reclaimed and Unhealthy if it failed to be reclaimed.
+func HealthReportForPersistentVolume(volume corev1.PersistentVolume) HealthReport {
+	report := NewHealthReport()
+	report.Kind = "PersistentVolume"
+	report.Name = volume.Name
+
+	switch volume.Status.Phase {
+	case corev1.VolumePending:
+		report.Healthy = StatusWarn
+		report.Errors = append(report.Errors, "The volume is pending.")
+	case corev1.VolumeReleased:
+		// Only volumes with the Retain policy stay released so someone has to
+		// clean them up by hand.
+		report.Healthy = StatusWarn
+		report.Errors = append(report.Errors, fmt.Sprintf("The volume was released by the claim [%s] and has not been reclaimed.", claimName(volume.Spec.ClaimRef)))
+	case corev1.VolumeFailed:
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, fmt.Sprintf("The volume failed to be reclaimed: %s", volume.Status.Message))
+	}
+
+	if report.Healthy == StatusUnknown {
+		report.Healthy = StatusHealthy
+	}
+
+	return report
+}
+
+// HealthReportForPersistentVolumeClaim is Unhealthy if the claim lost its
+// volume or pods have been waiting on it to be bound for longer than the
+// pending threshold, and Warn if it isn't bound yet.
+func HealthReportForPersistentVolumeClaim(claim corev1.PersistentVolumeClaim, f informers.SharedInformerFactory) HealthReport {
+	report := NewHealthReport()
+	report.Kind = "PersistentVolumeClaim"
+	report.Namespace = claim.Namespace
+	report.Name = claim.Name
+	report.Tenant, report.Environment = parseTenantAndEnv(claim.Namespace)
+
+	switch claim.Status.Phase {
+	case corev1.ClaimLost:
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, fmt.Sprintf("The claim lost its volume [%s].", claim.Spec.VolumeName))
+	case corev1.ClaimPending:
+		pods, err := f.Core().V1().Pods().Lister().Pods(claim.Namespace).List(labels.Everything())
+		if err != nil {
+			report.Errors = append(report.Errors, "Failed to fetch Pods from Kubernetes.")
+			return report
+		}
+
+		blocked := make([]string, 0)
+		for _, pod := range pods {
+			if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || PodIsIgnored(*pod) {
+				continue
+			}
+			for _, volume := range pod.Spec.Volumes {
+				if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claim.Name {
+					blocked = append(blocked, pod.Name)
+					break
+				}
+			}
+		}
+		sort.Strings(blocked)
+
+		// Claims using WaitForFirstConsumer stay pending until their pod is
+		// scheduled so they get as long as a pending pod would.
+		if len(blocked) > 0 && time.Since(claim.CreationTimestamp.Time) > podPendingThreshold(claim.Namespace) {
+			report.Healthy = StatusUnhealthy
+			report.Errors = append(report.Errors, fmt.Sprintf("Pods are blocked waiting on the claim to be bound: [%s].", strings.Join(blocked, ",")))
+		} else if len(blocked) > 0 {
+			report.Healthy = StatusWarn
+			report.Errors = append(report.Errors, fmt.Sprintf("Pods are waiting on the claim to be bound: [%s].", strings.Join(blocked, ",")))
+		} else {
+			report.Healthy = StatusWarn
+			report.Errors = append(report.Errors, "The claim is not bound.")
+		}
+	}
+
+	if report.Healthy == StatusUnknown {
+		report.Healthy = StatusHealthy
+	}
+
+	return report
+}
+
+func claimName(ref *corev1.ObjectReference) string {
+	if ref == nil {
+		return ""
+	}
+	return ref.Namespace + "/" + ref.Name
+}
+
 func HealthReportForPod(pod corev1.Pod) HealthReport {
 	report := NewHealthReport()
 	report.Kind = "Pod"
@@ -234,26 +535,62 @@ func HealthReportForPod(pod corev1.Pod) HealthReport {
 	report.Tenant, report.Environment = parseTenantAndEnv(pod.Namespace)
 
 	// First check if this pod should be ignored...
-	for _, owner := range pod.OwnerReferences {
-		if owner.Kind == "Job" {
-			// We mark it Healthy so it doesn't mark a NS unhealthy.
-			report.Healthy = StatusHealthy
-			return report
-		}
+	if PodIsIgnored(pod) {
+		// We mark it Healthy so it doesn't mark a NS unhealthy.
+		report.Healthy = StatusHealthy
+		return report
 	}
-	for name, value := range pod.Labels {
-		if name == "jenkins" && value == "slave" {
-			// This pod is part of a jenkins job
+
+	// A pod that is being deleted only matters if it doesn't go away. Until
+	// its grace period is over it is shutting down like it was asked to.
+	if pod.DeletionTimestamp != nil {
+		if time.Now().Before(pod.DeletionTimestamp.Time) {
 			report.Healthy = StatusHealthy
 			return report
 		}
+		deleted := pod.DeletionTimestamp.Time
+		if pod.DeletionGracePeriodSeconds != nil {
+			// The timestamp is when the grace period ends, not when the pod
+			// was deleted.
+			deleted = deleted.Add(-time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second)
+		}
+		age := time.Since(deleted)
+		if age > podTerminatingThreshold(pod.Namespace) {
+			report.Healthy = StatusUnhealthy
+		} else {
+			report.Healthy = StatusWarn
+		}
+		report.Errors = append(report.Errors, fmt.Sprintf("Pod has been terminating for %s.", formatAge(age)))
+		return report
 	}
 
 	if pod.Status.Phase != corev1.PodSucceeded {
-		for _, condition := range pod.Status.Conditions {
-			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionFalse {
+		if errors := containerErrors(pod); len(errors) > 0 {
+			// The container errors say why the pod isn't ready so the generic
+			// PodReady message would only be noise.
+			report.Healthy = StatusUnhealthy
+			report.Errors = append(report.Errors, errors...)
+		} else if pod.Status.Phase == corev1.PodPending {
+			// Pods are pending for a bit while they are scheduled and their
+			// images are pulled so it takes a while for it to be a problem.
+			age := time.Since(pod.CreationTimestamp.Time)
+			if age > podPendingThreshold(pod.Namespace) {
 				report.Healthy = StatusUnhealthy
-				report.Errors = append(report.Errors, condition.Message)
+			} else if age > podPendingGrace {
+				report.Healthy = StatusWarn
+			}
+			if report.Healthy != StatusUnknown {
+				report.Errors = append(report.Errors, fmt.Sprintf("Pod has been pending for %s.", formatAge(age)))
+				if reason := pendingReason(pod); reason != "" {
+					report.Errors = append(report.Errors, reason)
+				}
+			}
+		} else {
+			for _, condition := range pod.Status.Conditions {
+				if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionFalse {
+					report.Healthy = StatusUnhealthy
+					report.Errors = append(report.Errors, condition.Message)
+				}
 			}
 		}
 	}
@@ -266,26 +603,158 @@ func HealthReportForPod(pod corev1.Pod) HealthReport {
 	return report
 }
 
+// pendingReason is why the scheduler couldn't place the pod or, if it was
+// placed, why it isn't ready yet.
+func pendingReason(pod corev1.Pod) string {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			return fmt.Sprintf("%s: %s", condition.Reason, condition.Message)
+		}
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionFalse {
+			return condition.Message
+		}
+	}
+	return ""
+}
+
+// formatAge rounds an age to the minute (ex: 20m, 1h5m, 3h) or, if it is less
+// than a minute, to the second.
+func formatAge(age time.Duration) string {
+	if age < time.Minute {
+		return age.Round(time.Second).String()
+	}
+	formatted := strings.TrimSuffix(age.Round(time.Minute).String(), "0s")
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+	return formatted
+}
+
+// containerWaitingFailures are the reasons a container can be waiting that
+// it won't get out of on its own.
+var containerWaitingFailures = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"RunContainerError":          true,
+}
+
+// containerErrors describes every init container and container in the pod
+// that is stuck waiting on a failure or has terminated with a non-zero exit
+// code (ex: OOMKilled).
+func containerErrors(pod corev1.Pod) []string {
+	errors := make([]string, 0)
+
+	check := func(kind string, status corev1.ContainerStatus) {
+		var msg string
+		switch {
+		case status.State.Waiting != nil && containerWaitingFailures[status.State.Waiting.Reason]:
+			msg = fmt.Sprintf("%s [%s] is in %s with %d restarts", kind, status.Name, status.State.Waiting.Reason, status.RestartCount)
+			if last := status.LastTerminationState.Terminated; last != nil && last.Reason != "" {
+				msg += fmt.Sprintf(", last terminated with %s (exit code %d)", last.Reason, last.ExitCode)
+			}
+			if status.State.Waiting.Message != "" {
+				msg += ": " + strings.TrimSuffix(status.State.Waiting.Message, ".")
+			}
+		case status.State.Terminated != nil && status.State.Terminated.ExitCode != 0:
+			msg = fmt.Sprintf("%s [%s] terminated with %s (exit code %d) with %d restarts", kind, status.Name, status.State.Terminated.Reason, status.State.Terminated.ExitCode, status.RestartCount)
+		default:
+			return
+		}
+		errors = append(errors, msg+".")
+	}
+
+	for _, status := range pod.Status.InitContainerStatuses {
+		check("Init container", status)
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		check("Container", status)
+	}
+
+	return errors
+}
+
+// HealthReportForService checks the service's Endpoints. It is Unhealthy if
+// none of its addresses are ready and Warn if only some of them are.
 func HealthReportForService(service corev1.Service, f informers.SharedInformerFactory) HealthReport {
-	//   - A service is considered unhealthy if no pods are handling requests
+	endpoints, err := f.Core().V1().Endpoints().Lister().Endpoints(service.Namespace).Get(service.Name)
+	if err != nil && !k8errors.IsNotFound(err) {
+		report := HealthReportForServiceEndpoints(service, nil, f)
+		report.Healthy = StatusUnknown
+		report.Errors = []string{"Failed to fetch Endpoints from Kubernetes."}
+		return report
+	}
+
+	// The lister returns a nil Endpoints when it isn't found.
+	return HealthReportForServiceEndpoints(service, endpoints, f)
+}
+
+// HealthReportForServiceEndpoints is HealthReportForService with the
+// service's Endpoints (or nil if it has none) already in hand.
+func HealthReportForServiceEndpoints(service corev1.Service, endpoints *corev1.Endpoints, f informers.SharedInformerFactory) HealthReport {
 	report := NewHealthReport()
-	report.Kind = service.Kind
+	report.Kind = "Service"
 	report.Namespace = service.Namespace
 	report.Name = service.Name
 	report.Tenant, report.Environment = parseTenantAndEnv(service.Namespace)
 
-	selector := labels.SelectorFromSet(labels.Set(service.Spec.Selector))
-	pods, err := f.Core().V1().Pods().Lister().Pods(service.Namespace).List(selector)
-	if err == nil {
-		var podReport HealthReport
-		for _, pod := range pods {
-			podReport = HealthReportForPod(*pod)
-			if podReport.Healthy == StatusHealthy {
-				// We are good if even a single pod is Ready
-				report.Healthy = StatusHealthy
-				break
+	// ExternalName services are a DNS alias without endpoints of their own.
+	if service.Spec.Type == corev1.ServiceTypeExternalName {
+		report.Healthy = StatusHealthy
+		return report
+	}
+
+	// Services without a selector have their endpoints managed by hand (or
+	// not at all) so there is nothing for us to check.
+	if len(service.Spec.Selector) == 0 {
+		report.Healthy = StatusHealthy
+		return report
+	}
+
+	if endpoints == nil {
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, "The service does not have any endpoints.")
+		return report
+	}
+
+	// Addresses of ignored pods are left out. Ignored pods are always
+	// "healthy" so they can't vouch for us.
+	var ready, notReady, ignored int
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			if addressIsIgnored(address, service.Namespace, f) {
+				ignored++
+			} else {
+				ready++
 			}
 		}
+		for _, address := range subset.NotReadyAddresses {
+			if addressIsIgnored(address, service.Namespace, f) {
+				ignored++
+			} else {
+				notReady++
+			}
+		}
+	}
+
+	switch {
+	case ready == 0 && notReady == 0 && ignored > 0:
+		// Every pod behind the service is ignored so we ignore it too.
+	case ready == 0 && notReady == 0:
+		report.Healthy = StatusUnhealthy
+		selector := labels.SelectorFromSet(labels.Set(service.Spec.Selector))
+		report.Errors = append(report.Errors, fmt.Sprintf("No ready pods match the selector [%s].", selector.String()))
+	case ready == 0:
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, fmt.Sprintf("None of the endpoints [%d] are ready.", notReady))
+	case notReady > 0:
+		report.Healthy = StatusWarn
+		report.Errors = append(report.Errors, fmt.Sprintf("Only %d of the endpoints [%d] are ready.", ready, ready+notReady))
 	}
 
 	if report.Healthy == StatusUnknown {
@@ -295,17 +764,30 @@ func HealthReportForService(service corev1.Service, f informers.SharedInformerFa
 	return report
 }
 
+// addressIsIgnored returns whether the address belongs to a pod that matches
+// the ignore rules.
+func addressIsIgnored(address corev1.EndpointAddress, namespace string, f informers.SharedInformerFactory) bool {
+	if address.TargetRef == nil || address.TargetRef.Kind != "Pod" {
+		return false
+	}
+	if address.TargetRef.Namespace != "" {
+		namespace = address.TargetRef.Namespace
+	}
+	pod, err := f.Core().V1().Pods().Lister().Pods(namespace).Get(address.TargetRef.Name)
+	if err != nil {
+		return false
+	}
+	return PodIsIgnored(*pod)
+}
+
 func HealthReportForStatefulSet(statefulset appsv1.StatefulSet) HealthReport {
 	report := NewHealthReport()
-	report.Kind = statefulset.Kind
+	report.Kind = "StatefulSet"
 	report.Namespace = statefulset.Namespace
 	report.Name = statefulset.Name
 	report.Tenant, report.Environment = parseTenantAndEnv(statefulset.Namespace)
 
-	if int32(*statefulset.Spec.Replicas) != int32(statefulset.Status.ReadyReplicas) {
-		report.Healthy = StatusUnhealthy
-		report.Errors = append(report.Errors, fmt.Sprintf("The number of desired replicas [%d] does not match the number of ready replicas [%d].", statefulset.Spec.Replicas, statefulset.Status.ReadyReplicas))
-	}
+	checkReadyReplicas(&report, "replicas", desiredReplicas(statefulset.Spec.Replicas), statefulset.Status.ReadyReplicas)
 
 	if report.Healthy == StatusUnknown {
 		report.Healthy = StatusHealthy
@@ -313,3 +795,69 @@ func HealthReportForStatefulSet(statefulset appsv1.StatefulSet) HealthReport {
 
 	return report
 }
+
+// checkReadyReplicas marks the report Unhealthy if none of the desired
+// replicas are ready and Warn if only some of them are.
+func checkReadyReplicas(report *HealthReport, noun string, desired int32, ready int32) {
+	switch {
+	case desired > 0 && ready == 0:
+		escalate(report, StatusUnhealthy)
+		report.Errors = append(report.Errors, fmt.Sprintf("None of the desired %s [%d] are ready.", noun, desired))
+	case ready < desired:
+		escalate(report, StatusWarn)
+		report.Errors = append(report.Errors, fmt.Sprintf("The number of desired %s [%d] does not match the number of ready %s [%d].", noun, desired, noun, ready))
+	}
+}
+
+// desiredReplicas dereferences spec.replicas which defaults to 1 when unset.
+func desiredReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// escalate raises the report to status but never lowers it, so a Warn found
+// later can't hide an Unhealthy found earlier.
+func escalate(report *HealthReport, status HealthyStatus) {
+	if severity(status) > severity(report.Healthy) {
+		report.Healthy = status
+	}
+}
+
+func severity(status HealthyStatus) int {
+	switch status {
+	case StatusUnhealthy:
+		return 2
+	case StatusWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// rollUp adds the health of a namespace's objects of one kind to the
+// namespace's report. Any Unhealthy object makes the namespace Unhealthy and
+// any Warn object makes it Warn.
+func rollUp(nsreport *HealthReport, kind string, reports []HealthReport) {
+	unhealthy := make([]string, 0)
+	warn := make([]string, 0)
+	for _, report := range reports {
+		switch report.Healthy {
+		case StatusHealthy:
+		case StatusWarn:
+			warn = append(warn, report.Name)
+		default:
+			unhealthy = append(unhealthy, report.Name)
+		}
+	}
+
+	if len(unhealthy) > 0 {
+		escalate(nsreport, StatusUnhealthy)
+		nsreport.Errors = append(nsreport.Errors, fmt.Sprintf("%s with unhealthy status: [%s].", kind, strings.Join(unhealthy, ",")))
+	}
+	if len(warn) > 0 {
+		escalate(nsreport, StatusWarn)
+		nsreport.Errors = append(nsreport.Errors, fmt.Sprintf("%s with warn status: [%s].", kind, strings.Join(warn, ",")))
+	}
+}
@@ -0,0 +1,96 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func genStatefulSet(name string, replicas int32, ready int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: name},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{ReadyReplicas: ready},
+	}
+}
+
+func TestHealthReportForReplicas(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		desired int32
+		ready   int32
+		healthy HealthyStatus
+	}{
+		{desc: "with all replicas ready", desired: 3, ready: 3, healthy: StatusHealthy},
+		{desc: "with some replicas ready", desired: 3, ready: 1, healthy: StatusWarn},
+		{desc: "with no replicas ready", desired: 3, ready: 0, healthy: StatusUnhealthy},
+		{desc: "scaled to zero", desired: 0, ready: 0, healthy: StatusHealthy},
+	}
+
+	for _, tc := range testCases {
+		statefulset := HealthReportForStatefulSet(*genStatefulSet("db", tc.desired, tc.ready))
+		assert.Equal(t, tc.healthy, statefulset.Healthy, "statefulset "+tc.desc)
+
+		daemonset := HealthReportForDaemonSet(appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: "agent"},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: tc.desired, NumberReady: tc.ready},
+		})
+		assert.Equal(t, tc.healthy, daemonset.Healthy, "daemonset "+tc.desc)
+	}
+}
+
+func TestHealthReportForNamespace(t *testing.T) {
+	namespace := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app-dev"}}
+
+	testCases := []struct {
+		desc         string
+		statefulsets []*appsv1.StatefulSet
+		claims       []*corev1.PersistentVolumeClaim
+		healthy      HealthyStatus
+		errors       []string
+	}{{
+		desc:         "with healthy statefulsets",
+		statefulsets: []*appsv1.StatefulSet{genStatefulSet("db", 3, 3)},
+		healthy:      StatusHealthy,
+	}, {
+		desc:         "with a degraded statefulset",
+		statefulsets: []*appsv1.StatefulSet{genStatefulSet("db", 3, 3), genStatefulSet("cache", 3, 2)},
+		healthy:      StatusWarn,
+		errors:       []string{"StatefulSets with warn status: [cache]."},
+	}, {
+		desc:         "with degraded and unhealthy statefulsets",
+		statefulsets: []*appsv1.StatefulSet{genStatefulSet("db", 3, 0), genStatefulSet("cache", 3, 2)},
+		healthy:      StatusUnhealthy,
+		errors:       []string{"StatefulSets with unhealthy status: [db].", "StatefulSets with warn status: [cache]."},
+	}, {
+		desc: "with lost and pending claims",
+		claims: []*corev1.PersistentVolumeClaim{
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: "db-data"}, Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimLost}},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: "cache-data"}, Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending}},
+		},
+		healthy: StatusUnhealthy,
+		errors:  []string{"PersistentVolumeClaims with unhealthy status: [db-data].", "PersistentVolumeClaims with warn status: [cache-data]."},
+	}}
+
+	for _, tc := range testCases {
+		factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+		indexer := factory.Apps().V1().StatefulSets().Informer().GetIndexer()
+		for _, statefulset := range tc.statefulsets {
+			indexer.Add(statefulset)
+		}
+		claims := factory.Core().V1().PersistentVolumeClaims().Informer().GetIndexer()
+		for _, claim := range tc.claims {
+			claims.Add(claim)
+		}
+
+		result := HealthReportForNamespace(namespace, factory)
+		assert.Equal(t, tc.healthy, result.Healthy, tc.desc)
+		assert.ElementsMatch(t, tc.errors, result.Errors, tc.desc)
+	}
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// IgnoreAnnotation can be set to "true" on a pod to exempt it from health
+// checks with the default ignore rules.
+const IgnoreAnnotation = "bms.io/ignore"
+
+// IgnoreRule exempts the pods it matches from health checks. A pod matches if
+// it matches every field that is set, and a rule with no fields set matches
+// nothing. Ex: ignore gitlab runners and tekton task pods:
+//
+//	ignore_pods:
+//	  - selector: app=gitlab-runner
+//	  - owner_kinds: [TaskRun]
+//	    namespaces: ["tekton-*"]
+type IgnoreRule struct {
+	Selector    string            `json:"selector,omitempty"`    // A label selector (ex: "jenkins=slave").
+	OwnerKinds  []string          `json:"owner_kinds,omitempty"` // Matches if any owner is one of these kinds.
+	Namespaces  []string          `json:"namespaces,omitempty"`  // Globs (ex: "ci-*") of which any must match.
+	Annotations map[string]string `json:"annotations,omitempty"` // All must be set to these values.
+}
+
+// DefaultIgnoreRules are always used unless ignore_pods_replace_defaults is
+// set. They ignore the pods of Jobs, jenkins build agents and pods annotated
+// with IgnoreAnnotation.
+var DefaultIgnoreRules = []IgnoreRule{
+	{OwnerKinds: []string{"Job"}},
+	{Selector: "jenkins=slave"},
+	{Annotations: map[string]string{IgnoreAnnotation: "true"}},
+}
+
+type ignoreRule struct {
+	IgnoreRule
+	selector labels.Selector
+}
+
+// compileIgnoreRules parses the selectors of rules. A rule that fails to parse
+// is left out and the first such error is returned.
+func compileIgnoreRules(rules []IgnoreRule) ([]ignoreRule, error) {
+	var firstErr error
+	compiled := make([]ignoreRule, 0, len(rules))
+	for idx, rule := range rules {
+		if rule.Selector == "" && len(rule.OwnerKinds) == 0 && len(rule.Namespaces) == 0 && len(rule.Annotations) == 0 {
+			continue
+		}
+
+		selector := labels.Everything()
+		if rule.Selector != "" {
+			var err error
+			if selector, err = labels.Parse(rule.Selector); err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("Failed to parse selector of ignore rule %d: %w", idx, err)
+				}
+				continue
+			}
+		}
+		if err := checkNamespacePatterns(rule.Namespaces); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("Failed to parse namespaces of ignore rule %d: %w", idx, err)
+			}
+			continue
+		}
+
+		compiled = append(compiled, ignoreRule{IgnoreRule: rule, selector: selector})
+	}
+
+	return compiled, firstErr
+}
+
+func checkNamespacePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid pattern [%s]: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (rule ignoreRule) matches(pod corev1.Pod) bool {
+	if !rule.selector.Matches(labels.Set(pod.Labels)) {
+		return false
+	}
+
+	if len(rule.OwnerKinds) > 0 {
+		found := false
+		for _, owner := range pod.OwnerReferences {
+			for _, kind := range rule.OwnerKinds {
+				if owner.Kind == kind {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(rule.Namespaces) > 0 {
+		found := false
+		for _, pattern := range rule.Namespaces {
+			if matched, _ := path.Match(pattern, pod.Namespace); matched {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for key, value := range rule.Annotations {
+		if pod.Annotations[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PodIsIgnored returns whether the pod matches any of the configured ignore
+// rules or those in its namespace's bms ConfigMap.
+func PodIsIgnored(pod corev1.Pod) bool {
+	for _, rule := range currentIgnoreRules() {
+		if rule.matches(pod) {
+			return true
+		}
+	}
+	for _, rule := range namespaceSettingsFor(pod.Namespace).ignoreRules {
+		if rule.matches(pod) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodIsIgnored(t *testing.T) {
+	defer LoadConfig(Config{})
+
+	genIgnorePod := func(namespace string, labels map[string]string, annotations map[string]string, ownerKind string) corev1.Pod {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "pod-1", Labels: labels, Annotations: annotations}}
+		if ownerKind != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner"}}
+		}
+		return pod
+	}
+
+	rules := []IgnoreRule{
+		{Selector: "app in (gitlab-runner)"},
+		{OwnerKinds: []string{"TaskRun"}, Namespaces: []string{"tekton-*"}},
+		{Annotations: map[string]string{"example.com/ephemeral": "yes"}},
+		{}, // Matches nothing
+	}
+
+	testCases := []struct {
+		desc    string
+		rules   []IgnoreRule
+		replace bool
+		pod     corev1.Pod
+		ignored bool
+	}{
+		{desc: "default rules with a job pod", pod: genIgnorePod("app-dev", nil, nil, "Job"), ignored: true},
+		{desc: "default rules with a jenkins agent", pod: genIgnorePod("app-dev", map[string]string{"jenkins": "slave"}, nil, ""), ignored: true},
+		{desc: "default rules with the ignore annotation", pod: genIgnorePod("app-dev", nil, map[string]string{IgnoreAnnotation: "true"}, ""), ignored: true},
+		{desc: "default rules with a replicaset pod", pod: genIgnorePod("app-dev", map[string]string{"app": "api"}, nil, "ReplicaSet"), ignored: false},
+		{desc: "with a matching selector", rules: rules, pod: genIgnorePod("ci", map[string]string{"app": "gitlab-runner"}, nil, ""), ignored: true},
+		{desc: "with an owner kind in a matching namespace", rules: rules, pod: genIgnorePod("tekton-pipelines", nil, nil, "TaskRun"), ignored: true},
+		{desc: "with an owner kind in another namespace", rules: rules, pod: genIgnorePod("app-dev", nil, nil, "TaskRun"), ignored: false},
+		{desc: "with a matching annotation", rules: rules, pod: genIgnorePod("app-dev", nil, map[string]string{"example.com/ephemeral": "yes"}, ""), ignored: true},
+		{desc: "with rules added to the defaults", rules: rules, pod: genIgnorePod("app-dev", nil, nil, "Job"), ignored: true},
+		{desc: "with rules replacing the defaults", rules: rules, replace: true, pod: genIgnorePod("app-dev", nil, nil, "Job"), ignored: false},
+		{desc: "with the defaults replaced by nothing", replace: true, pod: genIgnorePod("app-dev", map[string]string{"jenkins": "slave"}, nil, ""), ignored: false},
+	}
+
+	for _, tc := range testCases {
+		assert.NoError(t, LoadConfig(Config{IgnorePods: tc.rules, IgnorePodsReplaceDefaults: tc.replace}), tc.desc)
+		assert.Equal(t, tc.ignored, PodIsIgnored(tc.pod), tc.desc)
+	}
+}
+
+func TestLoadConfigWithInvalidIgnoreRules(t *testing.T) {
+	defer LoadConfig(Config{})
+
+	err := LoadConfig(Config{IgnorePods: []IgnoreRule{
+		{Selector: "app in (gitlab-runner"},
+		{Namespaces: []string{"ci-["}},
+		{Selector: "app=tekton"},
+	}})
+	assert.Error(t, err)
+
+	// The valid rules still apply.
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "pod-1", Labels: map[string]string{"app": "tekton"}}}
+	assert.True(t, PodIsIgnored(pod))
+}
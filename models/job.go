@@ -0,0 +1,37 @@
+package models
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/client-go/informers"
+)
+
+type Job struct {
+	Name        string        `json:"name"`
+	Namespace   string        `json:"namespace"`
+	Tenant      string        `json:"tenant,omitempty"`
+	Environment string        `json:"environment,omitempty"`
+	Healthy     HealthyStatus `json:"healthy"`
+	Errors      []string      `json:"errors,omitempty"`
+}
+
+func FromK8Job(job batchv1.Job, factory informers.SharedInformerFactory) Job {
+	var (
+		report              HealthReport
+		tenant, environment string
+	)
+
+	// Get tenant info
+	tenant, environment = parseTenantAndEnv(job.Namespace)
+
+	// Get health report
+	report = HealthReportForJob(job, factory)
+
+	return Job{
+		Name:        job.Name,
+		Namespace:   job.Namespace,
+		Tenant:      tenant,
+		Environment: environment,
+		Healthy:     report.Healthy,
+		Errors:      report.Errors,
+	}
+}
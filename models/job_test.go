@@ -0,0 +1,197 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFromK8Job(t *testing.T) {
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+
+	testCases := []struct {
+		desc    string
+		status  batchv1.JobStatus
+		healthy HealthyStatus
+		errors  []string
+	}{{
+		desc: "with a completed job",
+		status: batchv1.JobStatus{
+			Succeeded:  1,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+		},
+		healthy: StatusHealthy,
+	}, {
+		desc:    "with a running job",
+		status:  batchv1.JobStatus{Active: 1},
+		healthy: StatusHealthy,
+	}, {
+		desc:    "with a job that is retrying",
+		status:  batchv1.JobStatus{Active: 1, Failed: 2},
+		healthy: StatusWarn,
+		errors:  []string{"The Job is retrying after 2 failed pods."},
+	}, {
+		desc: "with a failed job",
+		status: batchv1.JobStatus{
+			Failed: 6,
+			Conditions: []batchv1.JobCondition{{
+				Type:    batchv1.JobFailed,
+				Status:  corev1.ConditionTrue,
+				Reason:  "BackoffLimitExceeded",
+				Message: "Job has reached the specified backoff limit",
+			}},
+		},
+		healthy: StatusUnhealthy,
+		errors:  []string{"BackoffLimitExceeded: Job has reached the specified backoff limit"},
+	}}
+
+	for _, tc := range testCases {
+		job := batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: "migrate"},
+			Status:     tc.status,
+		}
+		result := FromK8Job(job, factory)
+		assert.Equal(t, tc.healthy, result.Healthy, tc.desc)
+		assert.Equal(t, tc.errors, result.Errors, tc.desc)
+		assert.Equal(t, "app", result.Tenant, tc.desc)
+	}
+}
+
+func TestFromK8JobWithNewerRun(t *testing.T) {
+	now := time.Now()
+	genJob := func(name string, started time.Time, condition batchv1.JobConditionType) *batchv1.Job {
+		start := metav1.NewTime(started)
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "app-dev",
+				Name:            name,
+				OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "backup"}},
+			},
+			Status: batchv1.JobStatus{
+				StartTime:  &start,
+				Conditions: []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit"}},
+			},
+		}
+	}
+
+	oldFailed := genJob("backup-1", now.Add(-2*time.Hour), batchv1.JobFailed)
+	completed := genJob("backup-2", now.Add(-time.Hour), batchv1.JobComplete)
+	newFailed := genJob("backup-3", now.Add(-time.Minute), batchv1.JobFailed)
+
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	jobs := factory.Batch().V1().Jobs().Informer().GetIndexer()
+	jobs.Add(oldFailed)
+	jobs.Add(completed)
+	jobs.Add(newFailed)
+
+	result := FromK8Job(*oldFailed, factory)
+	assert.Equal(t, StatusHealthy, result.Healthy, "a failed run older than a completed run is ignored")
+	assert.Empty(t, result.Errors, "a failed run older than a completed run is ignored")
+
+	result = FromK8Job(*newFailed, factory)
+	assert.Equal(t, StatusUnhealthy, result.Healthy, "a failed run newer than a completed run is unhealthy")
+}
+
+func TestFromK8CronJob(t *testing.T) {
+	defer LoadConfig(Config{})
+
+	now := time.Now()
+	genJob := func(cronjob string, started time.Time, condition batchv1.JobConditionType) *batchv1.Job {
+		start := metav1.NewTime(started)
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "app-dev",
+				Name:            cronjob + "-" + started.Format("150405"),
+				OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: cronjob}},
+			},
+			Status: batchv1.JobStatus{
+				StartTime:  &start,
+				Conditions: []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	jobs := factory.Batch().V1().Jobs().Informer().GetIndexer()
+	jobs.Add(genJob("fresh", now.Add(-30*time.Minute), batchv1.JobComplete))
+	jobs.Add(genJob("stale", now.Add(-3*time.Hour), batchv1.JobComplete))
+	jobs.Add(genJob("stale", now.Add(-30*time.Minute), batchv1.JobFailed))
+
+	suspend := true
+	noHistory := int32(0)
+
+	testCases := []struct {
+		desc    string
+		name    string
+		spec    batchv1.CronJobSpec
+		created time.Duration
+		config  Config
+		healthy HealthyStatus
+		errors  []string
+	}{{
+		desc:    "with a recent successful run",
+		name:    "fresh",
+		spec:    batchv1.CronJobSpec{Schedule: "0 * * * *"},
+		created: 24 * time.Hour,
+		healthy: StatusHealthy,
+	}, {
+		desc:    "with an old successful run",
+		name:    "stale",
+		spec:    batchv1.CronJobSpec{Schedule: "0 * * * *"},
+		created: 24 * time.Hour,
+		healthy: StatusUnhealthy,
+		errors:  []string{"The last successful run of the CronJob started 3h ago."},
+	}, {
+		desc:    "with an old successful run and a larger multiple",
+		name:    "stale",
+		spec:    batchv1.CronJobSpec{Schedule: "0 * * * *"},
+		created: 24 * time.Hour,
+		config:  Config{Jobs: JobsConfig{CronJobMultiple: 4}},
+		healthy: StatusHealthy,
+	}, {
+		desc:    "with a new cronjob",
+		name:    "new",
+		spec:    batchv1.CronJobSpec{Schedule: "0 0 * * *"},
+		created: time.Hour,
+		healthy: StatusHealthy,
+	}, {
+		desc:    "without a successful run",
+		name:    "new",
+		spec:    batchv1.CronJobSpec{Schedule: "@hourly"},
+		created: 5 * time.Hour,
+		healthy: StatusUnhealthy,
+		errors:  []string{"The CronJob has not had a successful run in the 5h since it was created."},
+	}, {
+		desc:    "without any history kept",
+		name:    "new",
+		spec:    batchv1.CronJobSpec{Schedule: "@hourly", SuccessfulJobsHistoryLimit: &noHistory},
+		created: 5 * time.Hour,
+		healthy: StatusHealthy,
+	}, {
+		desc:    "with a suspended cronjob",
+		name:    "stale",
+		spec:    batchv1.CronJobSpec{Schedule: "0 * * * *", Suspend: &suspend},
+		created: 24 * time.Hour,
+		healthy: StatusWarn,
+		errors:  []string{"The CronJob is suspended."},
+	}}
+
+	for _, tc := range testCases {
+		LoadConfig(tc.config)
+		cronjob := batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: tc.name, CreationTimestamp: metav1.NewTime(now.Add(-tc.created))},
+			Spec:       tc.spec,
+		}
+		result := FromK8CronJob(cronjob, factory)
+		assert.Equal(t, tc.healthy, result.Healthy, tc.desc)
+		assert.Equal(t, tc.errors, result.Errors, tc.desc)
+		assert.Equal(t, tc.spec.Schedule, result.Schedule, tc.desc)
+	}
+}
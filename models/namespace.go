@@ -1,8 +1,6 @@
 package models
 
 import (
-	"strings"
-
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/informers"
 )
@@ -14,12 +12,16 @@ type Namespace struct {
 	Healthy HealthyStatus `json:"healthy"`
 	Errors  []string      `json:"errors,omitempty"`
 
+	// From the namespace's bms ConfigMap
+	Contacts []Contact `json:"contacts,omitempty"`
+	Runbooks []Runbook `json:"runbooks,omitempty"`
+
 	Deployments []Deployment `json:"deployments"`
 }
 
 // Takes in a corev1.Namespace from k8 and builds a Namespace.
 func FromK8Namespace(input *corev1.Namespace, factory informers.SharedInformerFactory) Namespace {
-	tenant, env := parseTenantAndEnv(input.Name)
+	tenant, env := tenantAndEnvForNamespace(input)
 	report, err := HealthReportFor(input, factory)
 	if err != nil {
 		report = HealthReport{
@@ -36,25 +38,13 @@ func FromK8Namespace(input *corev1.Namespace, factory informers.SharedInformerFa
 		Errors:  report.Errors,
 	}
 
-	// TODO: Get bms configmap
-	// Setup values from config
-
-	return ns
-}
-
-func parseTenantAndEnv(name string) (string, string) {
-	tenant := "platform"
-	env := ""
-
-	// See if we can set Tenant/Env from Name
-	if strings.Contains(name, "-") {
-		parts := strings.Split(name, "-")
-		switch last := parts[len(parts)-1]; last {
-		case "cola", "demo", "dev", "int", "ivv", "pat", "pdt", "perf", "preprod", "prod", "prodtest", "sqa", "test", "uat":
-			env = last
-			tenant = strings.Join(parts[:len(parts)-1], "-")
-		}
+	// Setup values from the bms ConfigMap
+	if local, err := BMSConfigFor(input.Name); err == nil {
+		ns.Contacts = local.Contacts
+		ns.Runbooks = local.Runbooks
+	} else {
+		ns.Errors = append(ns.Errors, err.Error())
 	}
 
-	return tenant, env
+	return ns
 }
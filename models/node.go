@@ -1,15 +1,25 @@
 package models
 
 import (
+	"math"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
+// ResourceQuantities is the amount of a resource on a node. Allocated is the
+// sum of the requests of pods scheduled on the node and Limits the sum of
+// their limits. The percentages are of Allocatable and can go over 100 when
+// the node is overcommitted.
 type ResourceQuantities struct {
-	Allocatable resource.Quantity `json:"allocatable"`
-	Allocated   resource.Quantity `json:"allocated"`
-	Utilized    resource.Quantity `json:"utilized"`
+	Allocatable      resource.Quantity `json:"allocatable"`
+	Allocated        resource.Quantity `json:"allocated"`
+	AllocatedPercent float64           `json:"allocated_percent"`
+	Limits           resource.Quantity `json:"limits"`
+	LimitsPercent    float64           `json:"limits_percent"`
+	Utilized         resource.Quantity `json:"utilized"`
+	UtilizedPercent  float64           `json:"utilized_percent"`
 }
 
 type Node struct {
@@ -58,8 +68,87 @@ func FromK8Node(node corev1.Node) Node {
 func (n *Node) AddMetrics(metrics metricsv1beta1.NodeMetrics) {
 	if usage, ok := metrics.Usage["cpu"]; ok {
 		n.CPU.Utilized = usage
+		n.CPU.UtilizedPercent = percent(usage, n.CPU.Allocatable)
 	}
 	if usage, ok := metrics.Usage["memory"]; ok {
 		n.Memory.Utilized = usage
+		n.Memory.UtilizedPercent = percent(usage, n.Memory.Allocatable)
+	}
+}
+
+// AddPods sums the requests and limits of the pods scheduled on the node into
+// Allocated and Limits. Pods on other nodes and pods that have finished are
+// skipped so the whole pod list can be passed in.
+func (n *Node) AddPods(pods []*corev1.Pod) {
+	requests := corev1.ResourceList{}
+	limits := corev1.ResourceList{}
+
+	for _, pod := range pods {
+		if pod.Spec.NodeName != n.Name || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podRequests, podLimits := podResources(*pod)
+		addResources(requests, podRequests)
+		addResources(limits, podLimits)
+	}
+
+	n.CPU.Allocated = requests[corev1.ResourceCPU]
+	n.CPU.AllocatedPercent = percent(n.CPU.Allocated, n.CPU.Allocatable)
+	n.CPU.Limits = limits[corev1.ResourceCPU]
+	n.CPU.LimitsPercent = percent(n.CPU.Limits, n.CPU.Allocatable)
+	n.Memory.Allocated = requests[corev1.ResourceMemory]
+	n.Memory.AllocatedPercent = percent(n.Memory.Allocated, n.Memory.Allocatable)
+	n.Memory.Limits = limits[corev1.ResourceMemory]
+	n.Memory.LimitsPercent = percent(n.Memory.Limits, n.Memory.Allocatable)
+}
+
+// podResources returns the requests and limits of a pod the same way the
+// scheduler counts them: the sum of its containers or the largest init
+// container, whichever is bigger, plus the pod overhead.
+func podResources(pod corev1.Pod) (requests corev1.ResourceList, limits corev1.ResourceList) {
+	requests = corev1.ResourceList{}
+	limits = corev1.ResourceList{}
+
+	for _, container := range pod.Spec.Containers {
+		addResources(requests, container.Resources.Requests)
+		addResources(limits, container.Resources.Limits)
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		maxResources(requests, container.Resources.Requests)
+		maxResources(limits, container.Resources.Limits)
+	}
+
+	addResources(requests, pod.Spec.Overhead)
+	addResources(limits, pod.Spec.Overhead)
+
+	return
+}
+
+func addResources(list corev1.ResourceList, add corev1.ResourceList) {
+	for name, quantity := range add {
+		if current, ok := list[name]; ok {
+			current.Add(quantity)
+			list[name] = current
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func maxResources(list corev1.ResourceList, other corev1.ResourceList) {
+	for name, quantity := range other {
+		if current, ok := list[name]; !ok || quantity.Cmp(current) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// percent returns part as a percentage of whole rounded to two decimal places.
+func percent(part resource.Quantity, whole resource.Quantity) float64 {
+	if whole.IsZero() {
+		return 0
 	}
+	result := float64(part.MilliValue()) / float64(whole.MilliValue()) * 100
+	return math.Round(result*100) / 100
 }
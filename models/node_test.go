@@ -80,3 +80,50 @@ func TestAddMetrics(t *testing.T) {
 	assert.Equal(t, "1", node.CPU.Utilized.String())
 	assert.Equal(t, "1Mi", node.Memory.Utilized.String())
 }
+
+func TestAddPods(t *testing.T) {
+	container := func(request string, limit string) corev1.Container {
+		resources := corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(request), corev1.ResourceMemory: resource.MustParse(request + "Gi")},
+		}
+		if limit != "" {
+			resources.Limits = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(limit), corev1.ResourceMemory: resource.MustParse(limit + "Gi")}
+		}
+		return corev1.Container{Resources: resources}
+	}
+
+	pods := []*corev1.Pod{{
+		// Two containers
+		Spec: corev1.PodSpec{NodeName: "node1", Containers: []corev1.Container{container("1", "2"), container("1", "")}},
+	}, {
+		// An init container bigger than the containers counts instead of them.
+		Spec: corev1.PodSpec{
+			NodeName:       "node1",
+			InitContainers: []corev1.Container{container("3", "4")},
+			Containers:     []corev1.Container{container("1", "1")},
+		},
+	}, {
+		// On another node
+		Spec: corev1.PodSpec{NodeName: "node2", Containers: []corev1.Container{container("8", "8")}},
+	}, {
+		// Finished
+		Spec:   corev1.PodSpec{NodeName: "node1", Containers: []corev1.Container{container("8", "8")}},
+		Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+	}}
+
+	node := Node{
+		Name:   "node1",
+		CPU:    ResourceQuantities{Allocatable: resource.MustParse("4")},
+		Memory: ResourceQuantities{Allocatable: resource.MustParse("16Gi")},
+	}
+	node.AddPods(pods)
+
+	assert.Equal(t, "5", node.CPU.Allocated.String())
+	assert.Equal(t, 125.0, node.CPU.AllocatedPercent, "cpu requests should be overcommitted")
+	assert.Equal(t, "6", node.CPU.Limits.String())
+	assert.Equal(t, 150.0, node.CPU.LimitsPercent)
+	assert.Equal(t, "5Gi", node.Memory.Allocated.String())
+	assert.Equal(t, 31.25, node.Memory.AllocatedPercent)
+	assert.Equal(t, "6Gi", node.Memory.Limits.String())
+	assert.Equal(t, 37.5, node.Memory.LimitsPercent)
+}
@@ -0,0 +1,207 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFromK8Pod(t *testing.T) {
+	notReady := []corev1.PodCondition{{
+		Type:    corev1.PodReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  "ContainersNotReady",
+		Message: "containers with unready status: [api]",
+	}}
+
+	testCases := []struct {
+		desc    string
+		age     time.Duration
+		status  corev1.PodStatus
+		healthy HealthyStatus
+		errors  []string
+	}{{
+		desc: "with a ready pod",
+		status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "api",
+				Ready: true,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		},
+		healthy: StatusHealthy,
+	}, {
+		desc: "with a container that is crash looping after being OOMKilled",
+		status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: notReady,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "api",
+				RestartCount: 12,
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+					Reason:  "CrashLoopBackOff",
+					Message: "back-off 5m0s restarting failed container=api pod=api-1_app-dev(1234)",
+				}},
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+			}},
+		},
+		healthy: StatusUnhealthy,
+		errors:  []string{"Container [api] is in CrashLoopBackOff with 12 restarts, last terminated with OOMKilled (exit code 137): back-off 5m0s restarting failed container=api pod=api-1_app-dev(1234)."},
+	}, {
+		desc: "with an image that can't be pulled",
+		status: corev1.PodStatus{
+			Phase:      corev1.PodPending,
+			Conditions: notReady,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "api",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: `Back-off pulling image "api:missing".`}},
+			}},
+		},
+		healthy: StatusUnhealthy,
+		errors:  []string{`Container [api] is in ImagePullBackOff with 0 restarts: Back-off pulling image "api:missing".`},
+	}, {
+		desc: "with a failing init container",
+		status: corev1.PodStatus{
+			Phase:      corev1.PodPending,
+			Conditions: notReady,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "migrate",
+				RestartCount: 1,
+				State:        corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}},
+			}},
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "api",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"}},
+			}},
+		},
+		healthy: StatusUnhealthy,
+		errors:  []string{"Init container [migrate] terminated with Error (exit code 1) with 1 restarts."},
+	}, {
+		desc: "with a container that is starting",
+		age:  2 * time.Minute,
+		status: corev1.PodStatus{
+			Phase:      corev1.PodPending,
+			Conditions: notReady,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "api",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+			}},
+		},
+		healthy: StatusWarn,
+		errors:  []string{"Pod has been pending for 2m.", "containers with unready status: [api]"},
+	}, {
+		desc: "with a pod that can't be scheduled",
+		age:  25 * time.Minute,
+		status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{{
+				Type:    corev1.PodScheduled,
+				Status:  corev1.ConditionFalse,
+				Reason:  "Unschedulable",
+				Message: "0/3 nodes are available: 3 Insufficient cpu.",
+			}},
+		},
+		healthy: StatusUnhealthy,
+		errors:  []string{"Pod has been pending for 25m.", "Unschedulable: 0/3 nodes are available: 3 Insufficient cpu."},
+	}, {
+		desc: "with a completed pod",
+		status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "api",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}},
+			}},
+		},
+		healthy: StatusHealthy,
+	}}
+
+	for _, tc := range testCases {
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: "api-1", CreationTimestamp: metav1.NewTime(time.Now().Add(-tc.age))},
+			Status:     tc.status,
+		}
+		result := FromK8Pod(pod)
+		assert.Equal(t, tc.healthy, result.Healthy, tc.desc)
+		assert.Equal(t, tc.errors, result.Errors, tc.desc)
+	}
+}
+
+func TestFromK8PodThresholds(t *testing.T) {
+	defer LoadConfig(Config{})
+
+	grace := int64(30)
+	pending := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: "api-1", CreationTimestamp: metav1.NewTime(time.Now().Add(-30 * time.Minute))},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	terminating := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:                  "app-dev",
+			Name:                       "api-2",
+			DeletionTimestamp:          &metav1.Time{Time: time.Now().Add(-30*time.Minute + 30*time.Second)},
+			DeletionGracePeriodSeconds: &grace,
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+
+	testCases := []struct {
+		desc        string
+		config      Config
+		pending     HealthyStatus
+		terminating HealthyStatus
+	}{
+		{desc: "with the default thresholds", config: Config{}, pending: StatusUnhealthy, terminating: StatusUnhealthy},
+		{desc: "with longer thresholds", config: Config{Pods: PodsConfig{PendingThreshold: "1h", TerminatingThreshold: "1d"}}, pending: StatusWarn, terminating: StatusWarn},
+	}
+
+	for _, tc := range testCases {
+		LoadConfig(tc.config)
+		assert.Equal(t, tc.pending, FromK8Pod(pending).Healthy, "pending "+tc.desc)
+		result := FromK8Pod(terminating)
+		assert.Equal(t, tc.terminating, result.Healthy, "terminating "+tc.desc)
+		assert.Equal(t, []string{"Pod has been terminating for 30m."}, result.Errors, tc.desc)
+	}
+
+	// Pods that were just created or are still in their grace period are
+	// expected to be pending or terminating.
+	pending.CreationTimestamp = metav1.NewTime(time.Now().Add(-30 * time.Second))
+	result := FromK8Pod(pending)
+	assert.Equal(t, StatusHealthy, result.Healthy, "pending for less than the grace")
+	assert.Empty(t, result.Errors, "pending for less than the grace")
+	pending.CreationTimestamp = metav1.NewTime(time.Now().Add(-5 * time.Minute))
+	assert.Equal(t, StatusWarn, FromK8Pod(pending).Healthy, "pending for more than the grace")
+
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(20 * time.Second)}
+	result = FromK8Pod(terminating)
+	assert.Equal(t, StatusHealthy, result.Healthy, "terminating within the grace period")
+	assert.Empty(t, result.Errors, "terminating within the grace period")
+}
+
+func TestLoadConfigWithInvalidThresholds(t *testing.T) {
+	defer LoadConfig(Config{})
+
+	testCases := []struct {
+		desc   string
+		pods   PodsConfig
+		errMsg string
+	}{
+		{desc: "with valid thresholds", pods: PodsConfig{PendingThreshold: "15m", TerminatingThreshold: "1d"}},
+		{desc: "with an unparsable threshold", pods: PodsConfig{PendingThreshold: "10 minutes"}, errMsg: "Invalid pods.pending_threshold [10 minutes]"},
+		{desc: "with a negative threshold", pods: PodsConfig{TerminatingThreshold: "-5m"}, errMsg: "Invalid pods.terminating_threshold [-5m]"},
+	}
+
+	for _, tc := range testCases {
+		err := LoadConfig(Config{Pods: tc.pods})
+		if tc.errMsg == "" {
+			assert.NoError(t, err, tc.desc)
+		} else if assert.Error(t, err, tc.desc) {
+			assert.Contains(t, err.Error(), tc.errMsg, tc.desc)
+		}
+	}
+}
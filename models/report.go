@@ -1,26 +1,65 @@
 package models
 
 import (
+	"sort"
 	"time"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 type ReportSummary struct {
 	Timestamp int64          `json:"timestamp"`
 	Date      time.Time      `json:"date"`
+	Healthy   HealthyStatus  `json:"healthy"`
 	Errors    []string       `json:"errors"`
 	Counts    map[string]int `json:"counts"`
 }
 
 type Report struct {
+	Key                   string          `json:"key,omitempty"`         // Set once the report has been stored.
+	Tenant                string          `json:"tenant,omitempty"`      // Set if the report is limited to a tenant.
+	Environment           string          `json:"environment,omitempty"` // Set if the report is limited to an environment.
 	Date                  time.Time       `json:"date"`
+	Scheduled             time.Time       `json:"scheduled"` // The run of report_schedule the report was generated for. Zero if it wasn't scheduled.
+	Healthy               HealthyStatus   `json:"healthy"`   // The worst status of anything in the report.
 	Errors                []string        `json:"errors"`
 	Nodes                 []Node          `json:"nodes"`
+	UnhealthyCronJobs     []CronJob       `json:"unhealthy_cronjobs"`
 	UnhealthyDaemonSets   []DaemonSet     `json:"unhealthy_daemonsets"`
 	UnhealthyDeployments  []Deployment    `json:"unhealthy_deployments"`
+	UnhealthyJobs         []Job           `json:"unhealthy_jobs"`
+	UnhealthyNamespaces   []Namespace     `json:"unhealthy_namespaces"`
 	UnhealthyPods         []Pod           `json:"unhealthy_pods"`
+	UnhealthyServices     []Service       `json:"unhealthy_services"`
 	UnhealthyStatefulSets []StatefulSet   `json:"unhealthy_statefulsets"`
 	Restarts              []ReportRestart `json:"restarts"`
 	URLs                  []URLCheck      `json:"urlchecks"`
+	Velero                ReportVelero    `json:"velero"`
+	Objects               ReportObjects   `json:"objects"` // Every object the report looked at.
+}
+
+// ReportObjects holds the names of every object in a report per kind, healthy
+// or not, so a diff can tell an object that recovered from one that was
+// deleted. Namespaced objects are named as "namespace/name". Nodes are left
+// out since the report already lists all of them.
+type ReportObjects struct {
+	CronJobs     []string `json:"cronjobs"`
+	DaemonSets   []string `json:"daemonsets"`
+	Deployments  []string `json:"deployments"`
+	Jobs         []string `json:"jobs"`
+	Namespaces   []string `json:"namespaces"`
+	Pods         []string `json:"pods"`
+	Services     []string `json:"services"`
+	StatefulSets []string `json:"statefulsets"`
+}
+
+// ReportVelero is the velero section of a report.
+type ReportVelero struct {
+	// FailedBackups are the backups that failed within velero.max_backup_age.
+	FailedBackups []VeleroBackup `json:"failed_backups"`
+	// UnhealthySchedules are the schedules that failed validation or whose
+	// last successful backup is older than velero.max_backup_age.
+	UnhealthySchedules []VeleroSchedule `json:"unhealthy_schedules"`
 }
 
 func NewReport() Report {
@@ -28,33 +67,276 @@ func NewReport() Report {
 		Date:                  time.Now(),
 		Errors:                make([]string, 0),
 		Nodes:                 make([]Node, 0),
+		UnhealthyCronJobs:     make([]CronJob, 0),
 		UnhealthyDaemonSets:   make([]DaemonSet, 0),
 		UnhealthyDeployments:  make([]Deployment, 0),
+		UnhealthyJobs:         make([]Job, 0),
+		UnhealthyNamespaces:   make([]Namespace, 0),
 		UnhealthyPods:         make([]Pod, 0),
+		UnhealthyServices:     make([]Service, 0),
 		UnhealthyStatefulSets: make([]StatefulSet, 0),
 		Restarts:              make([]ReportRestart, 0),
 		URLs:                  make([]URLCheck, 0),
+		Velero: ReportVelero{
+			FailedBackups:      make([]VeleroBackup, 0),
+			UnhealthySchedules: make([]VeleroSchedule, 0),
+		},
+		Objects: ReportObjects{
+			CronJobs:     make([]string, 0),
+			DaemonSets:   make([]string, 0),
+			Deployments:  make([]string, 0),
+			Jobs:         make([]string, 0),
+			Namespaces:   make([]string, 0),
+			Pods:         make([]string, 0),
+			Services:     make([]string, 0),
+			StatefulSets: make([]string, 0),
+		},
 	}
 }
 
+// Summary counts the objects in each section of the report. Objects in the
+// unhealthy sections with a Warn status are counted under warn_* instead of
+// unhealthy_*.
 func (r *Report) Summary() ReportSummary {
+	cronjobs := make([]HealthyStatus, len(r.UnhealthyCronJobs))
+	for idx, cronjob := range r.UnhealthyCronJobs {
+		cronjobs[idx] = cronjob.Healthy
+	}
+	daemonsets := make([]HealthyStatus, len(r.UnhealthyDaemonSets))
+	for idx, daemonset := range r.UnhealthyDaemonSets {
+		daemonsets[idx] = daemonset.Healthy
+	}
+	deployments := make([]HealthyStatus, len(r.UnhealthyDeployments))
+	for idx, deployment := range r.UnhealthyDeployments {
+		deployments[idx] = deployment.Healthy
+	}
+	jobs := make([]HealthyStatus, len(r.UnhealthyJobs))
+	for idx, job := range r.UnhealthyJobs {
+		jobs[idx] = job.Healthy
+	}
+	namespaces := make([]HealthyStatus, len(r.UnhealthyNamespaces))
+	for idx, namespace := range r.UnhealthyNamespaces {
+		namespaces[idx] = namespace.Healthy
+	}
+	pods := make([]HealthyStatus, len(r.UnhealthyPods))
+	for idx, pod := range r.UnhealthyPods {
+		pods[idx] = pod.Healthy
+	}
+	services := make([]HealthyStatus, len(r.UnhealthyServices))
+	for idx, service := range r.UnhealthyServices {
+		services[idx] = service.Healthy
+	}
+	statefulsets := make([]HealthyStatus, len(r.UnhealthyStatefulSets))
+	for idx, statefulset := range r.UnhealthyStatefulSets {
+		statefulsets[idx] = statefulset.Healthy
+	}
+
+	healthy := r.Healthy
+	if healthy == "" {
+		// Reports stored before the field existed.
+		healthy = r.Status()
+	}
+
 	return ReportSummary{
 		Timestamp: r.Date.Unix(),
 		Date:      r.Date,
+		Healthy:   healthy,
 		Errors:    r.Errors,
 		Counts: map[string]int{
 			"nodes":                  len(r.Nodes),
-			"unhealthy_daemonsets":   len(r.UnhealthyDaemonSets),
-			"unhealthy_deployments":  len(r.UnhealthyDeployments),
-			"unhealthy_pods":         len(r.UnhealthyPods),
-			"unhealthy_statefulsets": len(r.UnhealthyStatefulSets),
+			"unhealthy_cronjobs":     len(cronjobs) - countWarn(cronjobs),
+			"unhealthy_daemonsets":   len(daemonsets) - countWarn(daemonsets),
+			"unhealthy_deployments":  len(deployments) - countWarn(deployments),
+			"unhealthy_jobs":         len(jobs) - countWarn(jobs),
+			"unhealthy_namespaces":   len(namespaces) - countWarn(namespaces),
+			"unhealthy_pods":         len(pods) - countWarn(pods),
+			"unhealthy_services":     len(services) - countWarn(services),
+			"unhealthy_statefulsets": len(statefulsets) - countWarn(statefulsets),
+			"warn_cronjobs":          countWarn(cronjobs),
+			"warn_daemonsets":        countWarn(daemonsets),
+			"warn_deployments":       countWarn(deployments),
+			"warn_jobs":              countWarn(jobs),
+			"warn_namespaces":        countWarn(namespaces),
+			"warn_pods":              countWarn(pods),
+			"warn_services":          countWarn(services),
+			"warn_statefulsets":      countWarn(statefulsets),
+			"failing_urlchecks":      r.failingURLChecks(),
+			"restarts":               len(r.Restarts),
+			"failed_backups":         len(r.Velero.FailedBackups),
+			"unhealthy_schedules":    len(r.Velero.UnhealthySchedules),
 		},
 	}
 }
 
+// Status rolls the report up to a single status. It is Unhealthy if anything
+// in the report is unhealthy, Warn if anything is only degraded and Healthy
+// otherwise. Restarts on their own don't change the status.
+func (r *Report) Status() HealthyStatus {
+	report := HealthReport{Healthy: StatusHealthy}
+	add := func(status HealthyStatus) {
+		switch status {
+		case StatusHealthy:
+		case StatusWarn:
+			escalate(&report, StatusWarn)
+		default:
+			escalate(&report, StatusUnhealthy)
+		}
+	}
+
+	for _, node := range r.Nodes {
+		add(node.Healthy)
+	}
+	for _, cronjob := range r.UnhealthyCronJobs {
+		add(cronjob.Healthy)
+	}
+	for _, daemonset := range r.UnhealthyDaemonSets {
+		add(daemonset.Healthy)
+	}
+	for _, deployment := range r.UnhealthyDeployments {
+		add(deployment.Healthy)
+	}
+	for _, job := range r.UnhealthyJobs {
+		add(job.Healthy)
+	}
+	for _, namespace := range r.UnhealthyNamespaces {
+		add(namespace.Healthy)
+	}
+	for _, pod := range r.UnhealthyPods {
+		add(pod.Healthy)
+	}
+	for _, service := range r.UnhealthyServices {
+		add(service.Healthy)
+	}
+	for _, statefulset := range r.UnhealthyStatefulSets {
+		add(statefulset.Healthy)
+	}
+	if r.failingURLChecks() > 0 {
+		add(StatusUnhealthy)
+	}
+	for _, backup := range r.Velero.FailedBackups {
+		add(backup.Healthy)
+	}
+	for _, schedule := range r.Velero.UnhealthySchedules {
+		add(schedule.Healthy)
+	}
+
+	return report.Healthy
+}
+
+func countWarn(statuses []HealthyStatus) int {
+	count := 0
+	for _, status := range statuses {
+		if status == StatusWarn {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *Report) failingURLChecks() int {
+	count := 0
+	for _, check := range r.URLs {
+		if check.Healthy == StatusUnhealthy {
+			count++
+		}
+	}
+	return count
+}
+
+// ReportTrend is the counts of a series of report summaries laid out for
+// charting. Series[name][i] is the count of name in the report at
+// Timestamps[i]. Since is zero if the series wasn't limited.
+type ReportTrend struct {
+	Since      time.Time        `json:"since"`
+	Timestamps []int64          `json:"timestamps"`
+	Series     map[string][]int `json:"series"`
+}
+
+// TrendOf turns report summaries, oldest first, into a ReportTrend. A count
+// missing from a summary is reported as 0.
+func TrendOf(summaries []ReportSummary) ReportTrend {
+	trend := ReportTrend{
+		Timestamps: make([]int64, len(summaries)),
+		Series:     map[string][]int{},
+	}
+
+	for idx, summary := range summaries {
+		trend.Timestamps[idx] = summary.Timestamp
+		for name, count := range summary.Counts {
+			if _, ok := trend.Series[name]; !ok {
+				trend.Series[name] = make([]int, len(summaries))
+			}
+			trend.Series[name][idx] = count
+		}
+	}
+
+	return trend
+}
+
+// ReportRestart is a container that has restarted more than the configured
+// restart_threshold. LastRestart is zero if the container has no record of
+// its last termination.
 type ReportRestart struct {
 	Namespace    string    `json:"namespace"`
-	Name         string    `json:"name"`
+	Pod          string    `json:"pod"`
+	Container    string    `json:"container"`
 	RestartCount uint      `json:"restart_count"`
-	LastRestart  time.Time `json:"last_restart,omitempty"`
+	Reason       string    `json:"reason,omitempty"` // Why the container last terminated (ex: OOMKilled, Error)
+	LastRestart  time.Time `json:"last_restart"`
+}
+
+// RestartsForPod returns the containers (including init containers) in the
+// pod that have restarted more than threshold times.
+func RestartsForPod(pod corev1.Pod, threshold int) []ReportRestart {
+	restarts := make([]ReportRestart, 0)
+
+	statuses := make([]corev1.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if int(status.RestartCount) <= threshold {
+			continue
+		}
+
+		restart := ReportRestart{
+			Namespace:    pod.Namespace,
+			Pod:          pod.Name,
+			Container:    status.Name,
+			RestartCount: uint(status.RestartCount),
+		}
+		if terminated := status.LastTerminationState.Terminated; terminated != nil {
+			restart.Reason = terminated.Reason
+			restart.LastRestart = terminated.FinishedAt.Time
+		}
+		restarts = append(restarts, restart)
+	}
+
+	return restarts
+}
+
+// SortRestarts orders restarts from the most restarts to the least.
+func SortRestarts(restarts []ReportRestart) {
+	sort.SliceStable(restarts, func(i, j int) bool {
+		if restarts[i].RestartCount != restarts[j].RestartCount {
+			return restarts[i].RestartCount > restarts[j].RestartCount
+		}
+		if restarts[i].Namespace != restarts[j].Namespace {
+			return restarts[i].Namespace < restarts[j].Namespace
+		}
+		if restarts[i].Pod != restarts[j].Pod {
+			return restarts[i].Pod < restarts[j].Pod
+		}
+		return restarts[i].Container < restarts[j].Container
+	})
+}
+
+// ReportScheduleStatus is the state of the in-process report scheduler.
+// LastRun is zero until the first run and NextRun is zero when nothing is
+// scheduled.
+type ReportScheduleStatus struct {
+	Schedule  string    `json:"schedule"`
+	LastRun   time.Time `json:"last_run"`
+	LastKey   string    `json:"last_key,omitempty"`
+	NextRun   time.Time `json:"next_run"`
+	LastError string    `json:"last_error,omitempty"`
 }
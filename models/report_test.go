@@ -0,0 +1,139 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRestartsForPod(t *testing.T) {
+	finished := time.Unix(1600000000, 0)
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: "api-1"},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: "migrate", RestartCount: 6},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:         "api",
+					RestartCount: 12,
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", FinishedAt: metav1.NewTime(finished)},
+					},
+				},
+				{Name: "sidecar", RestartCount: 5},
+			},
+		},
+	}
+
+	assert.Equal(t, []ReportRestart{
+		{Namespace: "app-dev", Pod: "api-1", Container: "migrate", RestartCount: 6},
+		{Namespace: "app-dev", Pod: "api-1", Container: "api", RestartCount: 12, Reason: "OOMKilled", LastRestart: finished},
+	}, RestartsForPod(pod, 5), "containers at the threshold should not be listed")
+
+	assert.Empty(t, RestartsForPod(pod, 20))
+}
+
+func TestSortRestarts(t *testing.T) {
+	restarts := []ReportRestart{
+		{Namespace: "b", Pod: "web", Container: "web", RestartCount: 6},
+		{Namespace: "a", Pod: "api", Container: "api", RestartCount: 12},
+		{Namespace: "a", Pod: "api", Container: "sidecar", RestartCount: 6},
+	}
+
+	SortRestarts(restarts)
+
+	assert.Equal(t, []ReportRestart{
+		{Namespace: "a", Pod: "api", Container: "api", RestartCount: 12},
+		{Namespace: "a", Pod: "api", Container: "sidecar", RestartCount: 6},
+		{Namespace: "b", Pod: "web", Container: "web", RestartCount: 6},
+	}, restarts)
+}
+
+func TestSummaryCounts(t *testing.T) {
+	report := NewReport()
+	report.Nodes = []Node{{Name: "node1"}, {Name: "node2"}}
+	report.UnhealthyPods = []Pod{{Name: "api-1"}}
+	report.URLs = []URLCheck{{Name: "grafana", Healthy: StatusHealthy}, {Name: "kibana", Healthy: StatusUnhealthy}, {Name: "vault", Healthy: StatusUnknown}}
+
+	counts := report.Summary().Counts
+	assert.Equal(t, 2, counts["nodes"])
+	assert.Equal(t, 1, counts["unhealthy_pods"])
+	assert.Equal(t, 0, counts["unhealthy_deployments"])
+	assert.Equal(t, 1, counts["failing_urlchecks"], "only checks that failed should count")
+
+	report.UnhealthyDeployments = []Deployment{{Name: "api", Healthy: StatusUnhealthy}, {Name: "web", Healthy: StatusWarn}}
+	counts = report.Summary().Counts
+	assert.Equal(t, 1, counts["unhealthy_deployments"])
+	assert.Equal(t, 1, counts["warn_deployments"])
+}
+
+func TestReportStatus(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		modify  func(report *Report)
+		healthy HealthyStatus
+	}{{
+		desc:    "with an empty report",
+		modify:  func(report *Report) {},
+		healthy: StatusHealthy,
+	}, {
+		desc: "with a degraded deployment",
+		modify: func(report *Report) {
+			report.UnhealthyDeployments = []Deployment{{Name: "web", Healthy: StatusWarn}}
+		},
+		healthy: StatusWarn,
+	}, {
+		desc: "with a degraded deployment and an unhealthy pod",
+		modify: func(report *Report) {
+			report.UnhealthyDeployments = []Deployment{{Name: "web", Healthy: StatusWarn}}
+			report.UnhealthyPods = []Pod{{Name: "api-1", Healthy: StatusUnhealthy}}
+		},
+		healthy: StatusUnhealthy,
+	}, {
+		desc: "with a failing url check",
+		modify: func(report *Report) {
+			report.URLs = []URLCheck{{Name: "grafana", Healthy: StatusUnhealthy}}
+		},
+		healthy: StatusUnhealthy,
+	}, {
+		desc: "with only restarts",
+		modify: func(report *Report) {
+			report.Restarts = []ReportRestart{{Pod: "api-1", RestartCount: 10}}
+		},
+		healthy: StatusHealthy,
+	}}
+
+	for _, tc := range testCases {
+		report := NewReport()
+		tc.modify(&report)
+		assert.Equal(t, tc.healthy, report.Status(), tc.desc)
+		assert.Equal(t, tc.healthy, report.Summary().Healthy, tc.desc)
+	}
+}
+
+func TestTrendOf(t *testing.T) {
+	summaries := []ReportSummary{
+		{Timestamp: 1600000000, Counts: map[string]int{"nodes": 3, "unhealthy_pods": 4}},
+		{Timestamp: 1600003600, Counts: map[string]int{"nodes": 3, "unhealthy_pods": 2, "failing_urlchecks": 1}},
+		{Timestamp: 1600007200, Counts: map[string]int{"nodes": 4, "unhealthy_pods": 0}},
+	}
+
+	trend := TrendOf(summaries)
+
+	assert.Equal(t, []int64{1600000000, 1600003600, 1600007200}, trend.Timestamps)
+	assert.Equal(t, map[string][]int{
+		"nodes":             {3, 3, 4},
+		"unhealthy_pods":    {4, 2, 0},
+		"failing_urlchecks": {0, 1, 0},
+	}, trend.Series)
+
+	empty := TrendOf([]ReportSummary{})
+	assert.Equal(t, []int64{}, empty.Timestamps)
+	assert.Equal(t, map[string][]int{}, empty.Series)
+}
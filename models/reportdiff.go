@@ -0,0 +1,243 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// ReportDiff is the difference between two reports. An object is unhealthy if
+// it has any status other than True, so Unhealthy and Recovered only track
+// objects going to and from True. A change between Warn and False is not
+// reported.
+type ReportDiff struct {
+	From           time.Time         `json:"from"`
+	To             time.Time         `json:"to"`
+	Unhealthy      ReportDiffObjects `json:"unhealthy"` // Objects that became unhealthy.
+	Recovered      ReportDiffObjects `json:"recovered"` // Unhealthy objects that are still around and now healthy.
+	Removed        ReportDiffObjects `json:"removed"`   // Unhealthy objects that no longer exist.
+	NodesJoined    []string          `json:"nodes_joined"`
+	NodesLeft      []string          `json:"nodes_left"`
+	KubeletChanges []ReportDiffValue `json:"kubelet_changes"`
+	URLChanges     []ReportDiffValue `json:"url_changes"`
+}
+
+// ReportDiffObjects holds the names of objects per kind. Namespaced objects
+// are named as "namespace/name".
+type ReportDiffObjects struct {
+	CronJobs     []string `json:"cronjobs"`
+	DaemonSets   []string `json:"daemonsets"`
+	Deployments  []string `json:"deployments"`
+	Jobs         []string `json:"jobs"`
+	Namespaces   []string `json:"namespaces"`
+	Nodes        []string `json:"nodes"`
+	Pods         []string `json:"pods"`
+	Services     []string `json:"services"`
+	StatefulSets []string `json:"statefulsets"`
+}
+
+// ReportDiffValue is a single value that changed between two reports. An empty
+// From or To means the object was missing from that report.
+type ReportDiffValue struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DiffReports compares two reports and returns what changed going from the
+// from report to the to report.
+func DiffReports(from, to Report) ReportDiff {
+	diff := ReportDiff{
+		From:           from.Date,
+		To:             to.Date,
+		NodesJoined:    make([]string, 0),
+		NodesLeft:      make([]string, 0),
+		KubeletChanges: make([]ReportDiffValue, 0),
+		URLChanges:     make([]ReportDiffValue, 0),
+	}
+
+	// Workloads
+	var fromNames, toNames []string
+
+	fromNames, toNames = cronJobNames(from.UnhealthyCronJobs), cronJobNames(to.UnhealthyCronJobs)
+	diff.Unhealthy.CronJobs, diff.Recovered.CronJobs, diff.Removed.CronJobs = diffNames(fromNames, toNames, to.Objects.CronJobs)
+
+	fromNames, toNames = daemonSetNames(from.UnhealthyDaemonSets), daemonSetNames(to.UnhealthyDaemonSets)
+	diff.Unhealthy.DaemonSets, diff.Recovered.DaemonSets, diff.Removed.DaemonSets = diffNames(fromNames, toNames, to.Objects.DaemonSets)
+
+	fromNames, toNames = deploymentNames(from.UnhealthyDeployments), deploymentNames(to.UnhealthyDeployments)
+	diff.Unhealthy.Deployments, diff.Recovered.Deployments, diff.Removed.Deployments = diffNames(fromNames, toNames, to.Objects.Deployments)
+
+	fromNames, toNames = jobNames(from.UnhealthyJobs), jobNames(to.UnhealthyJobs)
+	diff.Unhealthy.Jobs, diff.Recovered.Jobs, diff.Removed.Jobs = diffNames(fromNames, toNames, to.Objects.Jobs)
+
+	fromNames, toNames = namespaceNames(from.UnhealthyNamespaces), namespaceNames(to.UnhealthyNamespaces)
+	diff.Unhealthy.Namespaces, diff.Recovered.Namespaces, diff.Removed.Namespaces = diffNames(fromNames, toNames, to.Objects.Namespaces)
+
+	fromNames, toNames = podNames(from.UnhealthyPods), podNames(to.UnhealthyPods)
+	diff.Unhealthy.Pods, diff.Recovered.Pods, diff.Removed.Pods = diffNames(fromNames, toNames, to.Objects.Pods)
+
+	fromNames, toNames = serviceNames(from.UnhealthyServices), serviceNames(to.UnhealthyServices)
+	diff.Unhealthy.Services, diff.Recovered.Services, diff.Removed.Services = diffNames(fromNames, toNames, to.Objects.Services)
+
+	fromNames, toNames = statefulSetNames(from.UnhealthyStatefulSets), statefulSetNames(to.UnhealthyStatefulSets)
+	diff.Unhealthy.StatefulSets, diff.Recovered.StatefulSets, diff.Removed.StatefulSets = diffNames(fromNames, toNames, to.Objects.StatefulSets)
+
+	// Nodes
+	fromNodes, toNodes := nodesByName(from.Nodes), nodesByName(to.Nodes)
+	fromUnhealthy, toUnhealthy, toNodeNames := make([]string, 0), make([]string, 0), make([]string, 0, len(toNodes))
+	for name, node := range fromNodes {
+		if node.Healthy != StatusHealthy {
+			fromUnhealthy = append(fromUnhealthy, name)
+		}
+		if _, ok := toNodes[name]; !ok {
+			diff.NodesLeft = append(diff.NodesLeft, name)
+		}
+	}
+	for name, node := range toNodes {
+		toNodeNames = append(toNodeNames, name)
+		if node.Healthy != StatusHealthy {
+			toUnhealthy = append(toUnhealthy, name)
+		}
+		if prev, ok := fromNodes[name]; !ok {
+			diff.NodesJoined = append(diff.NodesJoined, name)
+		} else if prev.KubeletVersion != node.KubeletVersion {
+			diff.KubeletChanges = append(diff.KubeletChanges, ReportDiffValue{Name: name, From: prev.KubeletVersion, To: node.KubeletVersion})
+		}
+	}
+	diff.Unhealthy.Nodes, diff.Recovered.Nodes, diff.Removed.Nodes = diffNames(fromUnhealthy, toUnhealthy, toNodeNames)
+	sort.Strings(diff.NodesJoined)
+	sort.Strings(diff.NodesLeft)
+	sortDiffValues(diff.KubeletChanges)
+
+	// URLs
+	fromURLs := make(map[string]HealthyStatus, len(from.URLs))
+	for _, check := range from.URLs {
+		fromURLs[check.Name] = check.Healthy
+	}
+	toURLs := make(map[string]HealthyStatus, len(to.URLs))
+	for _, check := range to.URLs {
+		toURLs[check.Name] = check.Healthy
+	}
+	for name, healthy := range toURLs {
+		if prev, ok := fromURLs[name]; !ok || prev != healthy {
+			diff.URLChanges = append(diff.URLChanges, ReportDiffValue{Name: name, From: string(prev), To: string(healthy)})
+		}
+	}
+	for name, healthy := range fromURLs {
+		if _, ok := toURLs[name]; !ok {
+			diff.URLChanges = append(diff.URLChanges, ReportDiffValue{Name: name, From: string(healthy)})
+		}
+	}
+	sortDiffValues(diff.URLChanges)
+
+	return diff
+}
+
+// diffNames compares the unhealthy objects of one kind in two reports. Objects
+// that are no longer unhealthy count as recovered if they are in present and
+// as removed if they are not. Reports saved before the objects were recorded
+// have a nil present, so everything counts as recovered.
+func diffNames(from, to, present []string) (unhealthy, recovered, removed []string) {
+	unhealthy, recovered, removed = missingFrom(from, to), missingFrom(to, from), make([]string, 0)
+	if present == nil {
+		return
+	}
+
+	gone := missingFrom(present, recovered)
+	recovered = missingFrom(gone, recovered)
+	return unhealthy, recovered, gone
+}
+
+// missingFrom returns a sorted list of the names in b that are not in a.
+func missingFrom(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	for _, name := range a {
+		seen[name] = true
+	}
+
+	results := make([]string, 0)
+	for _, name := range b {
+		if !seen[name] {
+			results = append(results, name)
+		}
+	}
+	sort.Strings(results)
+
+	return results
+}
+
+func sortDiffValues(values []ReportDiffValue) {
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+}
+
+func nodesByName(nodes []Node) map[string]Node {
+	results := make(map[string]Node, len(nodes))
+	for _, node := range nodes {
+		results[node.Name] = node
+	}
+	return results
+}
+
+func cronJobNames(cronjobs []CronJob) []string {
+	names := make([]string, len(cronjobs))
+	for idx, cronjob := range cronjobs {
+		names[idx] = cronjob.Namespace + "/" + cronjob.Name
+	}
+	return names
+}
+
+func daemonSetNames(daemonsets []DaemonSet) []string {
+	names := make([]string, len(daemonsets))
+	for idx, daemonset := range daemonsets {
+		names[idx] = daemonset.Namespace + "/" + daemonset.Name
+	}
+	return names
+}
+
+func deploymentNames(deployments []Deployment) []string {
+	names := make([]string, len(deployments))
+	for idx, deployment := range deployments {
+		names[idx] = deployment.Namespace + "/" + deployment.Name
+	}
+	return names
+}
+
+func jobNames(jobs []Job) []string {
+	names := make([]string, len(jobs))
+	for idx, job := range jobs {
+		names[idx] = job.Namespace + "/" + job.Name
+	}
+	return names
+}
+
+func namespaceNames(namespaces []Namespace) []string {
+	names := make([]string, len(namespaces))
+	for idx, namespace := range namespaces {
+		names[idx] = namespace.Name
+	}
+	return names
+}
+
+func podNames(pods []Pod) []string {
+	names := make([]string, len(pods))
+	for idx, pod := range pods {
+		names[idx] = pod.Namespace + "/" + pod.Name
+	}
+	return names
+}
+
+func serviceNames(services []Service) []string {
+	names := make([]string, len(services))
+	for idx, service := range services {
+		names[idx] = service.Namespace + "/" + service.Name
+	}
+	return names
+}
+
+func statefulSetNames(statefulsets []StatefulSet) []string {
+	names := make([]string, len(statefulsets))
+	for idx, statefulset := range statefulsets {
+		names[idx] = statefulset.Namespace + "/" + statefulset.Name
+	}
+	return names
+}
@@ -0,0 +1,88 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+)
+
+func TestDiffReports(t *testing.T) {
+	from := NewReport()
+	from.Date = time.Unix(1600000000, 0)
+	from.Nodes = []Node{
+		{Name: "node1", Healthy: StatusHealthy, KubeletVersion: "v1.18.9"},
+		{Name: "node2", Healthy: StatusUnhealthy, KubeletVersion: "v1.18.9"},
+		{Name: "node3", Healthy: StatusHealthy, KubeletVersion: "v1.18.9"},
+		{Name: "node5", Healthy: StatusUnhealthy, KubeletVersion: "v1.18.9"},
+	}
+	from.UnhealthyDeployments = []Deployment{{Namespace: "app-dev", Name: "api"}}
+	from.UnhealthyJobs = []Job{{Namespace: "app-dev", Name: "migrate"}}
+	from.UnhealthyPods = []Pod{{Namespace: "app-dev", Name: "api-1"}, {Namespace: "app-dev", Name: "api-2"}}
+	from.URLs = []URLCheck{{Name: "grafana", Healthy: StatusHealthy}, {Name: "kibana", Healthy: StatusUnhealthy}, {Name: "consul", Healthy: StatusHealthy}}
+
+	to := NewReport()
+	to.Date = time.Unix(1600003600, 0)
+	to.Nodes = []Node{
+		{Name: "node1", Healthy: StatusUnhealthy, KubeletVersion: "v1.19.7"},
+		{Name: "node2", Healthy: StatusHealthy, KubeletVersion: "v1.18.9"},
+		{Name: "node4", Healthy: StatusHealthy, KubeletVersion: "v1.19.7"},
+	}
+	to.UnhealthyPods = []Pod{{Namespace: "app-dev", Name: "api-2"}, {Namespace: "app-prod", Name: "web-1"}}
+	to.UnhealthyStatefulSets = []StatefulSet{{Namespace: "app-prod", Name: "db"}}
+	to.UnhealthyNamespaces = []Namespace{{Name: "app-prod"}}
+	to.UnhealthyServices = []Service{{Namespace: "app-prod", Name: "web"}}
+	to.UnhealthyCronJobs = []CronJob{{Namespace: "app-prod", Name: "cleanup"}}
+	to.URLs = []URLCheck{{Name: "grafana", Healthy: StatusUnhealthy}, {Name: "kibana", Healthy: StatusHealthy}, {Name: "vault", Healthy: StatusHealthy}}
+	// The migrate job and api-1 pod were deleted.
+	to.Objects.Deployments = []string{"app-dev/api"}
+	to.Objects.Pods = []string{"app-dev/api-2", "app-prod/web-1"}
+
+	diff := DiffReports(from, to)
+
+	assert.Equal(t, from.Date, diff.From)
+	assert.Equal(t, to.Date, diff.To)
+
+	assert.Equal(t, []string{}, diff.Unhealthy.Deployments, "unhealthy deployments")
+	assert.Equal(t, []string{"app-dev/api"}, diff.Recovered.Deployments, "recovered deployments")
+	assert.Equal(t, []string{"app-prod/web-1"}, diff.Unhealthy.Pods, "unhealthy pods")
+	assert.Equal(t, []string{}, diff.Recovered.Pods, "recovered pods")
+	assert.Equal(t, []string{"app-dev/api-1"}, diff.Removed.Pods, "removed pods")
+	assert.Equal(t, []string{"app-prod/db"}, diff.Unhealthy.StatefulSets, "unhealthy statefulsets")
+	assert.Equal(t, []string{}, diff.Recovered.StatefulSets, "recovered statefulsets")
+	assert.Equal(t, []string{"app-prod"}, diff.Unhealthy.Namespaces, "unhealthy namespaces")
+	assert.Equal(t, []string{"app-prod/web"}, diff.Unhealthy.Services, "unhealthy services")
+	assert.Equal(t, []string{"app-prod/cleanup"}, diff.Unhealthy.CronJobs, "unhealthy cronjobs")
+	assert.Equal(t, []string{}, diff.Recovered.Jobs, "recovered jobs")
+	assert.Equal(t, []string{"app-dev/migrate"}, diff.Removed.Jobs, "removed jobs")
+
+	assert.Equal(t, []string{"node1"}, diff.Unhealthy.Nodes, "unhealthy nodes")
+	assert.Equal(t, []string{"node2"}, diff.Recovered.Nodes, "recovered nodes")
+	assert.Equal(t, []string{"node4"}, diff.NodesJoined, "nodes joined")
+	assert.Equal(t, []string{"node5"}, diff.Removed.Nodes, "removed nodes")
+	assert.Equal(t, []string{"node3", "node5"}, diff.NodesLeft, "nodes left")
+	assert.Equal(t, []ReportDiffValue{{Name: "node1", From: "v1.18.9", To: "v1.19.7"}}, diff.KubeletChanges, "kubelet changes")
+
+	assert.Equal(t, []ReportDiffValue{
+		{Name: "consul", From: "True", To: ""},
+		{Name: "grafana", From: "True", To: "False"},
+		{Name: "kibana", From: "False", To: "True"},
+		{Name: "vault", From: "", To: "True"},
+	}, diff.URLChanges, "url changes")
+}
+
+// Reports saved before the objects were recorded can't tell a deleted object
+// from a recovered one.
+func TestDiffReportsWithoutObjects(t *testing.T) {
+	from := NewReport()
+	from.UnhealthyPods = []Pod{{Namespace: "app-dev", Name: "api-1"}}
+
+	to := NewReport()
+	to.Objects = ReportObjects{}
+
+	diff := DiffReports(from, to)
+
+	assert.Equal(t, []string{"app-dev/api-1"}, diff.Recovered.Pods, "recovered pods")
+	assert.Equal(t, []string{}, diff.Removed.Pods, "removed pods")
+}
@@ -0,0 +1,45 @@
+package models
+
+// ReportOptions narrow a report down to a single tenant and/or environment.
+// Empty fields match everything.
+type ReportOptions struct {
+	Tenant      string
+	Environment string
+}
+
+// Scoped returns true if the report is limited to a tenant or environment
+// rather than the whole cluster.
+func (ro ReportOptions) Scoped() bool {
+	return ro.Tenant != "" || ro.Environment != ""
+}
+
+// Matches returns true if an object with the tenant and environment belongs in
+// the report.
+func (ro ReportOptions) Matches(tenant string, environment string) bool {
+	if ro.Tenant != "" && ro.Tenant != tenant {
+		return false
+	}
+	if ro.Environment != "" && ro.Environment != environment {
+		return false
+	}
+	return true
+}
+
+// MatchesURLCheck returns true if the url check belongs in the report. In a
+// scoped report only checks tagged with the tenant are included. A check
+// tagged with a tenant but no environment is included for every environment.
+func (ro ReportOptions) MatchesURLCheck(check URLCheck) bool {
+	if !ro.Scoped() {
+		return true
+	}
+	if ro.Tenant != "" && ro.Tenant != check.Tenant {
+		return false
+	}
+	if check.Tenant == "" {
+		return false // Untagged checks belong to the whole cluster.
+	}
+	if ro.Environment != "" && check.Environment != "" && ro.Environment != check.Environment {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+)
+
+func TestReportOptionsMatches(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		opts     ReportOptions
+		tenant   string
+		env      string
+		expected bool
+	}{
+		{desc: "unscoped", opts: ReportOptions{}, tenant: "foo", env: "prod", expected: true},
+		{desc: "same tenant", opts: ReportOptions{Tenant: "foo"}, tenant: "foo", env: "dev", expected: true},
+		{desc: "other tenant", opts: ReportOptions{Tenant: "foo"}, tenant: "bar", env: "prod", expected: false},
+		{desc: "same tenant and env", opts: ReportOptions{Tenant: "foo", Environment: "prod"}, tenant: "foo", env: "prod", expected: true},
+		{desc: "same tenant other env", opts: ReportOptions{Tenant: "foo", Environment: "prod"}, tenant: "foo", env: "dev", expected: false},
+		{desc: "env only", opts: ReportOptions{Environment: "prod"}, tenant: "bar", env: "prod", expected: true},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, tc.opts.Matches(tc.tenant, tc.env), tc.desc)
+	}
+}
+
+func TestReportOptionsMatchesURLCheck(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		opts     ReportOptions
+		check    URLCheck
+		expected bool
+	}{
+		{desc: "unscoped with an untagged check", opts: ReportOptions{}, check: URLCheck{}, expected: true},
+		{desc: "scoped with an untagged check", opts: ReportOptions{Tenant: "foo"}, check: URLCheck{}, expected: false},
+		{desc: "tagged with the tenant", opts: ReportOptions{Tenant: "foo", Environment: "prod"}, check: URLCheck{Tenant: "foo"}, expected: true},
+		{desc: "tagged with the tenant and env", opts: ReportOptions{Tenant: "foo", Environment: "prod"}, check: URLCheck{Tenant: "foo", Environment: "prod"}, expected: true},
+		{desc: "tagged with another env", opts: ReportOptions{Tenant: "foo", Environment: "prod"}, check: URLCheck{Tenant: "foo", Environment: "dev"}, expected: false},
+		{desc: "tagged with another tenant", opts: ReportOptions{Tenant: "foo"}, check: URLCheck{Tenant: "bar"}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, tc.opts.MatchesURLCheck(tc.check), tc.desc)
+	}
+}
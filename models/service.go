@@ -0,0 +1,37 @@
+package models
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+)
+
+type Service struct {
+	Name        string        `json:"name"`
+	Namespace   string        `json:"namespace"`
+	Tenant      string        `json:"tenant,omitempty"`
+	Environment string        `json:"environment,omitempty"`
+	Healthy     HealthyStatus `json:"healthy"`
+	Errors      []string      `json:"errors,omitempty"`
+}
+
+func FromK8Service(service corev1.Service, factory informers.SharedInformerFactory) Service {
+	var (
+		report              HealthReport
+		tenant, environment string
+	)
+
+	// Get tenant info
+	tenant, environment = parseTenantAndEnv(service.Namespace)
+
+	// Get health report
+	report = HealthReportForService(service, factory)
+
+	return Service{
+		Name:        service.Name,
+		Namespace:   service.Namespace,
+		Tenant:      tenant,
+		Environment: environment,
+		Healthy:     report.Healthy,
+		Errors:      report.Errors,
+	}
+}
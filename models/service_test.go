@@ -0,0 +1,89 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func genPod(name string, labels map[string]string, ready corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: name, Labels: labels},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+		},
+	}
+}
+
+// genEndpoints builds the Endpoints of a service from the names of its ready
+// and not ready pods.
+func genEndpoints(name string, ready []string, notReady []string) *corev1.Endpoints {
+	address := func(pod string) corev1.EndpointAddress {
+		return corev1.EndpointAddress{IP: "10.0.0.1", TargetRef: &corev1.ObjectReference{Kind: "Pod", Namespace: "app-dev", Name: pod}}
+	}
+
+	subset := corev1.EndpointSubset{}
+	for _, pod := range ready {
+		subset.Addresses = append(subset.Addresses, address(pod))
+	}
+	for _, pod := range notReady {
+		subset.NotReadyAddresses = append(subset.NotReadyAddresses, address(pod))
+	}
+
+	endpoints := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: name}}
+	if len(ready) > 0 || len(notReady) > 0 {
+		endpoints.Subsets = []corev1.EndpointSubset{subset}
+	}
+	return endpoints
+}
+
+func TestFromK8Service(t *testing.T) {
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	pods := factory.Core().V1().Pods().Informer().GetIndexer()
+	pods.Add(genPod("agent-1", map[string]string{"app": "agent", "jenkins": "slave"}, corev1.ConditionFalse))
+	endpoints := factory.Core().V1().Endpoints().Informer().GetIndexer()
+	endpoints.Add(genEndpoints("api", []string{"api-1", "api-2"}, nil))
+	endpoints.Add(genEndpoints("web", []string{"web-1"}, []string{"web-2"}))
+	endpoints.Add(genEndpoints("db", nil, []string{"db-1"}))
+	endpoints.Add(genEndpoints("cache", nil, nil))
+	endpoints.Add(genEndpoints("agent", nil, []string{"agent-1"}))
+	endpoints.Add(genEndpoints("external-db", []string{}, []string{}))
+
+	selector := map[string]string{"app": "test"}
+
+	testCases := []struct {
+		desc    string
+		name    string
+		spec    corev1.ServiceSpec
+		healthy HealthyStatus
+		errors  []string
+	}{
+		{desc: "with all endpoints ready", name: "api", spec: corev1.ServiceSpec{Selector: selector}, healthy: StatusHealthy},
+		{desc: "with some endpoints ready", name: "web", spec: corev1.ServiceSpec{Selector: selector}, healthy: StatusWarn, errors: []string{"Only 1 of the endpoints [2] are ready."}},
+		{desc: "with no endpoints ready", name: "db", spec: corev1.ServiceSpec{Selector: selector}, healthy: StatusUnhealthy, errors: []string{"None of the endpoints [1] are ready."}},
+		{desc: "with no endpoints", name: "cache", spec: corev1.ServiceSpec{Selector: selector}, healthy: StatusUnhealthy, errors: []string{"No ready pods match the selector [app=test]."}},
+		{desc: "with only ignored pods", name: "agent", spec: corev1.ServiceSpec{Selector: selector}, healthy: StatusHealthy},
+		{desc: "without Endpoints", name: "missing", spec: corev1.ServiceSpec{Selector: selector}, healthy: StatusUnhealthy, errors: []string{"The service does not have any endpoints."}},
+		{desc: "without a selector or Endpoints", name: "missing", healthy: StatusHealthy},
+		{desc: "without a selector and with empty Endpoints", name: "external-db", healthy: StatusHealthy},
+		{desc: "with an ExternalName", name: "missing", spec: corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "db.example.com"}, healthy: StatusHealthy},
+	}
+
+	for _, tc := range testCases {
+		service := corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: tc.name},
+			Spec:       tc.spec,
+		}
+		result := FromK8Service(service, factory)
+		assert.Equal(t, tc.healthy, result.Healthy, tc.desc)
+		assert.Equal(t, tc.errors, result.Errors, tc.desc)
+		assert.Equal(t, "app", result.Tenant, tc.desc)
+		assert.Equal(t, "dev", result.Environment, tc.desc)
+	}
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// settings are the parts of the bms-api config used by the health reports.
+// The config package can't be imported from here so it hands them over with
+// LoadConfig whenever the config file is (re)loaded.
+var (
+	settings    = Config{}
+	ignoreRules = mustCompileIgnoreRules(DefaultIgnoreRules)
+	tenancies   = mustCompileTenancy(TenancyConfig{})
+	settingsMu  sync.RWMutex
+)
+
+// LoadConfig replaces the settings used by the health reports. Invalid ignore
+// rules and tenancy patterns are left out, and invalid thresholds fall back to
+// their defaults. Either is reported in the returned error but the rest of the
+// config is still loaded.
+func LoadConfig(config Config) error {
+	rules := config.IgnorePods
+	if !config.IgnorePodsReplaceDefaults {
+		rules = append(append([]IgnoreRule{}, DefaultIgnoreRules...), rules...)
+	}
+	compiledRules, err := compileIgnoreRules(rules)
+	compiledTenancy, tenancyErr := compileTenancy(config.Tenancy)
+	if err == nil {
+		err = tenancyErr
+	}
+	if err == nil {
+		err = validatePodsConfig(config.Pods)
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings = config
+	ignoreRules = compiledRules
+	tenancies = compiledTenancy
+
+	return err
+}
+
+func currentSettings() Config {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return settings
+}
+
+func currentIgnoreRules() []ignoreRule {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return ignoreRules
+}
+
+func currentTenancy() tenancy {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return tenancies
+}
+
+func mustCompileTenancy(config TenancyConfig) tenancy {
+	compiled, err := compileTenancy(config)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+func mustCompileIgnoreRules(rules []IgnoreRule) []ignoreRule {
+	compiled, err := compileIgnoreRules(rules)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// podPendingGrace is how long a new pod may be pending before it is worth a
+// Warn. Scheduling the pod and pulling its images usually fits in it.
+const podPendingGrace = time.Minute
+
+// validatePodsConfig returns an error for the first threshold that isn't a
+// positive duration.
+func validatePodsConfig(config PodsConfig) error {
+	thresholds := []struct{ name, value string }{
+		{"pods.pending_threshold", config.PendingThreshold},
+		{"pods.terminating_threshold", config.TerminatingThreshold},
+	}
+	for _, threshold := range thresholds {
+		if threshold.value == "" {
+			continue
+		}
+		if parsed, err := ParseDuration(threshold.value); err != nil {
+			return fmt.Errorf("Invalid %s [%s]: %w", threshold.name, threshold.value, err)
+		} else if parsed <= 0 {
+			return fmt.Errorf("Invalid %s [%s]: it must be greater than zero.", threshold.name, threshold.value)
+		}
+	}
+	return nil
+}
+
+// podPendingThreshold is how long a pod in the namespace may be pending. The
+// namespace's bms ConfigMap wins over the bms-api config.
+func podPendingThreshold(namespace string) time.Duration {
+	local, _ := BMSConfigFor(namespace)
+	for _, value := range []string{local.Pods.PendingThreshold, currentSettings().Pods.PendingThreshold} {
+		if threshold, err := ParseDuration(value); err == nil && threshold > 0 {
+			return threshold
+		}
+	}
+	return 10 * time.Minute
+}
+
+// podTerminatingThreshold is how long a pod in the namespace may be
+// terminating. The namespace's bms ConfigMap wins over the bms-api config.
+func podTerminatingThreshold(namespace string) time.Duration {
+	local, _ := BMSConfigFor(namespace)
+	for _, value := range []string{local.Pods.TerminatingThreshold, currentSettings().Pods.TerminatingThreshold} {
+		if threshold, err := ParseDuration(value); err == nil && threshold > 0 {
+			return threshold
+		}
+	}
+	return 20 * time.Minute
+}
+
+// cronJobMultiple is jobs.cronjob_multiple for the namespace. The namespace's
+// bms ConfigMap wins over the bms-api config.
+func cronJobMultiple(namespace string) float64 {
+	local, _ := BMSConfigFor(namespace)
+	for _, value := range []float64{local.Jobs.CronJobMultiple, currentSettings().Jobs.CronJobMultiple} {
+		if value > 0 {
+			return value
+		}
+	}
+	return 2
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+)
+
+// TenantLabel and EnvironmentLabel can be set as labels or annotations on a
+// namespace to override the tenant and environment parsed from its name.
+const (
+	TenantLabel      = "bms.io/tenant"
+	EnvironmentLabel = "bms.io/environment"
+)
+
+// DefaultEnvironments are the namespace suffixes used when
+// tenancy.environments isn't set.
+var DefaultEnvironments = []string{"cola", "demo", "dev", "int", "ivv", "pat", "pdt", "perf", "preprod", "prod", "prodtest", "sqa", "test", "uat"}
+
+// TenancyConfig is how the tenant and environment of a namespace are worked
+// out. In order: the namespace's labels/annotations, its bms ConfigMap, the
+// first of Patterns that matches the name, then a "${tenant}-${env}" name with
+// an env from Environments. Anything else belongs to DefaultTenant. Ex:
+//
+//	tenancy:
+//	  environments: [dev, stage, sandbox, prod]
+//	  patterns:
+//	    - ^(?P<tenant>[a-z]+)-sb(?P<env>[0-9]+)$
+type TenancyConfig struct {
+	// Environments are the namespace suffixes that are environments. When
+	// set they replace DefaultEnvironments.
+	Environments []string `json:"environments,omitempty"`
+	// Patterns are regexes with the named groups tenant and/or env.
+	Patterns      []string `json:"patterns,omitempty"`
+	DefaultTenant string   `json:"default_tenant,omitempty"` // Default: platform
+}
+
+type tenancy struct {
+	environments  map[string]bool
+	patterns      []*regexp.Regexp
+	defaultTenant string
+}
+
+var namespaceLister listerscorev1.NamespaceLister
+
+// SetNamespaceLister gives the models a way to look up the labels and
+// annotations of a namespace from its name.
+func SetNamespaceLister(lister listerscorev1.NamespaceLister) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	namespaceLister = lister
+}
+
+// compileTenancy builds the tenancy mapping from config. A pattern that fails
+// to compile is left out and the first such error is returned.
+func compileTenancy(config TenancyConfig) (tenancy, error) {
+	var firstErr error
+	result := tenancy{
+		environments:  map[string]bool{},
+		patterns:      make([]*regexp.Regexp, 0, len(config.Patterns)),
+		defaultTenant: config.DefaultTenant,
+	}
+
+	environments := config.Environments
+	if environments == nil {
+		environments = DefaultEnvironments
+	}
+	for _, env := range environments {
+		result.environments[env] = true
+	}
+
+	for _, pattern := range config.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("Failed to compile tenancy pattern [%s]: %w", pattern, err)
+			}
+			continue
+		}
+		result.patterns = append(result.patterns, re)
+	}
+
+	if result.defaultTenant == "" {
+		result.defaultTenant = "platform"
+	}
+
+	return result, firstErr
+}
+
+// parse works out the tenant and environment from a namespace's name alone.
+func (t tenancy) parse(name string) (string, string) {
+	for _, re := range t.patterns {
+		match := re.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		tenant, env := t.defaultTenant, ""
+		if idx := re.SubexpIndex("tenant"); idx > 0 && match[idx] != "" {
+			tenant = match[idx]
+		}
+		if idx := re.SubexpIndex("env"); idx > 0 {
+			env = match[idx]
+		}
+		return tenant, env
+	}
+
+	// See if we can set Tenant/Env from Name
+	if strings.Contains(name, "-") {
+		parts := strings.Split(name, "-")
+		if last := parts[len(parts)-1]; t.environments[last] {
+			return strings.Join(parts[:len(parts)-1], "-"), last
+		}
+	}
+
+	return t.defaultTenant, ""
+}
+
+// parseTenantAndEnv returns the tenant and environment of the named namespace,
+// using its labels and annotations if it can be found.
+func parseTenantAndEnv(name string) (string, string) {
+	settingsMu.RLock()
+	lister := namespaceLister
+	settingsMu.RUnlock()
+
+	if lister != nil {
+		if namespace, err := lister.Get(name); err == nil {
+			return tenantAndEnvForNamespace(namespace)
+		}
+	}
+
+	return currentTenancy().parse(name)
+}
+
+// tenantAndEnvForNamespace is parseTenantAndEnv for a namespace we already
+// have. The namespace's bms ConfigMap wins over its name and its labels and
+// annotations win over both.
+func tenantAndEnvForNamespace(namespace *corev1.Namespace) (string, string) {
+	tenant, env := currentTenancy().parse(namespace.Name)
+	if local, err := BMSConfigFor(namespace.Name); err == nil {
+		if local.Tenant != "" {
+			tenant = local.Tenant
+		}
+		if local.Environment != "" {
+			env = local.Environment
+		}
+	}
+	if value, ok := namespaceOverride(namespace, TenantLabel); ok {
+		tenant = value
+	}
+	if value, ok := namespaceOverride(namespace, EnvironmentLabel); ok {
+		env = value
+	}
+	return tenant, env
+}
+
+// namespaceOverride looks up key in the namespace's labels, then annotations.
+func namespaceOverride(namespace *corev1.Namespace, key string) (string, bool) {
+	if value, ok := namespace.Labels[key]; ok {
+		return value, true
+	}
+	value, ok := namespace.Annotations[key]
+	return value, ok
+}
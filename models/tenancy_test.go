@@ -0,0 +1,59 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTenancy(t *testing.T) {
+	defer LoadConfig(Config{})
+	defer SetNamespaceLister(nil)
+
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	namespaces := factory.Core().V1().Namespaces().Informer().GetIndexer()
+	namespaces.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy-app", Labels: map[string]string{TenantLabel: "app", EnvironmentLabel: "prod"}}})
+	namespaces.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared-dev", Annotations: map[string]string{TenantLabel: "tools"}}})
+	SetNamespaceLister(factory.Core().V1().Namespaces().Lister())
+
+	tenancy := TenancyConfig{
+		Environments:  []string{"dev", "stage", "sandbox", "prod"},
+		Patterns:      []string{`^(?P<tenant>[a-z]+)-sb(?P<env>[0-9]+)$`},
+		DefaultTenant: "ops",
+	}
+
+	testCases := []struct {
+		desc      string
+		config    TenancyConfig
+		namespace string
+		tenant    string
+		env       string
+	}{
+		{desc: "default suffix", namespace: "app-dev", tenant: "app", env: "dev"},
+		{desc: "default suffix with dashes in the tenant", namespace: "my-app-prod", tenant: "my-app", env: "prod"},
+		{desc: "default tenant", namespace: "kube-system", tenant: "platform", env: ""},
+		{desc: "unknown suffix with the defaults", namespace: "app-stage", tenant: "platform", env: ""},
+		{desc: "configured suffix", config: tenancy, namespace: "app-stage", tenant: "app", env: "stage"},
+		{desc: "suffix no longer configured", config: tenancy, namespace: "app-uat", tenant: "ops", env: ""},
+		{desc: "pattern", config: tenancy, namespace: "app-sb2", tenant: "app", env: "2"},
+		{desc: "labels", config: tenancy, namespace: "legacy-app", tenant: "app", env: "prod"},
+		{desc: "annotations", namespace: "shared-dev", tenant: "tools", env: "dev"},
+	}
+
+	for _, tc := range testCases {
+		assert.NoError(t, LoadConfig(Config{Tenancy: tc.config}), tc.desc)
+		pod := FromK8Pod(corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: tc.namespace, Name: "pod-1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		})
+		assert.Equal(t, tc.tenant, pod.Tenant, tc.desc)
+		assert.Equal(t, tc.env, pod.Environment, tc.desc)
+	}
+
+	assert.Error(t, LoadConfig(Config{Tenancy: TenancyConfig{Patterns: []string{"app-(dev"}}}), "invalid patterns should be reported")
+}
@@ -28,17 +28,19 @@ func init() {
 // A URLCheck will treat a match against RegExp field as healthy unless FailTrue
 // is set which inverts the result.
 type URLCheck struct {
-	Name     string        `json:"name"`
-	Desc     string        `json:"description,omitempty"`
-	Url      string        `json:"url"`
-	Type     RespType      `json:"type"`
-	FailTrue bool          `json:"fail_true,omitempty"` // True will invert our result.
-	JSONPath string        `json:"jsonpath,omitempty"`  // Used for json Type.
-	RegExp   string        `json:"regexp,omitempty"`
-	Date     time.Time     `json:"date,omitempty"`
-	Healthy  HealthyStatus `json:"healthy"`
-	Text     string        `json:"text,omitempty"`
-	Errors   []string      `json:"errors,omitempty"`
+	Name        string        `json:"name"`
+	Desc        string        `json:"description,omitempty"`
+	Url         string        `json:"url"`
+	Tenant      string        `json:"tenant,omitempty"`      // Include the check in this tenant's reports.
+	Environment string        `json:"environment,omitempty"` // Limit Tenant to one environment. Empty is all of them.
+	Type        RespType      `json:"type"`
+	FailTrue    bool          `json:"fail_true,omitempty"` // True will invert our result.
+	JSONPath    string        `json:"jsonpath,omitempty"`  // Used for json Type.
+	RegExp      string        `json:"regexp,omitempty"`
+	Date        time.Time     `json:"date,omitempty"`
+	Healthy     HealthyStatus `json:"healthy"`
+	Text        string        `json:"text,omitempty"`
+	Errors      []string      `json:"errors,omitempty"`
 }
 
 // URLClientOpts is a struct to hold all configuration options needed for
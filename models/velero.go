@@ -0,0 +1,159 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+)
+
+// VeleroBackup is a velero Backup. StartTimestamp, CompletionTimestamp and
+// Expiration are zero until velero sets them.
+type VeleroBackup struct {
+	Name                string        `json:"name"`
+	Namespace           string        `json:"namespace"`
+	Schedule            string        `json:"schedule,omitempty"` // Name of the schedule that created the backup, if any.
+	Phase               string        `json:"phase"`
+	Healthy             HealthyStatus `json:"healthy"`
+	Errors              []string      `json:"errors,omitempty"`
+	Created             time.Time     `json:"created"`
+	StartTimestamp      time.Time     `json:"start_timestamp"`
+	CompletionTimestamp time.Time     `json:"completion_timestamp"`
+	Expiration          time.Time     `json:"expiration"`
+}
+
+// VeleroSchedule is a velero Schedule. LastBackup and LastSuccessfulBackup are
+// zero if the schedule hasn't created (or completed) a backup yet.
+type VeleroSchedule struct {
+	Name                 string        `json:"name"`
+	Namespace            string        `json:"namespace"`
+	Schedule             string        `json:"schedule"` // Cron expression
+	Phase                string        `json:"phase"`
+	Healthy              HealthyStatus `json:"healthy"`
+	Errors               []string      `json:"errors,omitempty"`
+	LastBackup           time.Time     `json:"last_backup"`
+	LastSuccessfulBackup time.Time     `json:"last_successful_backup"`
+}
+
+func FromVeleroBackup(backup velerov1.Backup) VeleroBackup {
+	report := HealthReportForBackup(backup)
+
+	result := VeleroBackup{
+		Name:      backup.Name,
+		Namespace: backup.Namespace,
+		Schedule:  backup.Labels[velerov1.ScheduleNameLabel],
+		Phase:     string(backup.Status.Phase),
+		Healthy:   report.Healthy,
+		Errors:    report.Errors,
+		Created:   backup.CreationTimestamp.Time,
+	}
+	if backup.Status.StartTimestamp != nil {
+		result.StartTimestamp = backup.Status.StartTimestamp.Time
+	}
+	if backup.Status.CompletionTimestamp != nil {
+		result.CompletionTimestamp = backup.Status.CompletionTimestamp.Time
+	}
+	if backup.Status.Expiration != nil {
+		result.Expiration = backup.Status.Expiration.Time
+	}
+
+	return result
+}
+
+// FromVeleroSchedule needs the backups in the schedule's namespace to find the
+// last successful backup it created. The schedule is unhealthy if that backup
+// finished more than maxAge before now.
+func FromVeleroSchedule(schedule velerov1.Schedule, backups []velerov1.Backup, maxAge time.Duration, now time.Time) VeleroSchedule {
+	report := HealthReportForSchedule(schedule, backups, maxAge, now)
+
+	result := VeleroSchedule{
+		Name:                 schedule.Name,
+		Namespace:            schedule.Namespace,
+		Schedule:             schedule.Spec.Schedule,
+		Phase:                string(schedule.Status.Phase),
+		Healthy:              report.Healthy,
+		Errors:               report.Errors,
+		LastSuccessfulBackup: lastSuccessfulBackup(schedule, backups),
+	}
+	if schedule.Status.LastBackup != nil {
+		result.LastBackup = schedule.Status.LastBackup.Time
+	}
+
+	return result
+}
+
+func HealthReportForBackup(backup velerov1.Backup) HealthReport {
+	report := NewHealthReport()
+	report.Kind = "Backup"
+	report.Namespace = backup.Namespace
+	report.Name = backup.Name
+
+	switch backup.Status.Phase {
+	case velerov1.BackupPhaseCompleted:
+		report.Healthy = StatusHealthy
+	case velerov1.BackupPhaseFailed:
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, fmt.Sprintf("Backup failed with %d errors.", backup.Status.Errors))
+	case velerov1.BackupPhasePartiallyFailed:
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, fmt.Sprintf("Backup partially failed with %d errors.", backup.Status.Errors))
+	case velerov1.BackupPhaseFailedValidation:
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, "Backup failed validation.")
+		report.Errors = append(report.Errors, backup.Status.ValidationErrors...)
+	default:
+		// New, InProgress, and Deleting are neither good nor bad yet.
+		report.Text = fmt.Sprintf("Backup is %s.", backup.Status.Phase)
+	}
+
+	return report
+}
+
+func HealthReportForSchedule(schedule velerov1.Schedule, backups []velerov1.Backup, maxAge time.Duration, now time.Time) HealthReport {
+	report := NewHealthReport()
+	report.Kind = "Schedule"
+	report.Namespace = schedule.Namespace
+	report.Name = schedule.Name
+
+	if schedule.Status.Phase == velerov1.SchedulePhaseFailedValidation {
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, "Schedule failed validation.")
+		report.Errors = append(report.Errors, schedule.Status.ValidationErrors...)
+		return report
+	}
+
+	last := lastSuccessfulBackup(schedule, backups)
+	switch {
+	case last.IsZero() && now.Sub(schedule.CreationTimestamp.Time) <= maxAge:
+		// Too new to have a successful backup yet.
+		report.Text = "Schedule has not completed a backup yet."
+	case last.IsZero():
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, "Schedule has no successful backups.")
+	case now.Sub(last) > maxAge:
+		report.Healthy = StatusUnhealthy
+		report.Errors = append(report.Errors, fmt.Sprintf("Last successful backup was %s ago which is older than the max age of %s.", now.Sub(last).Round(time.Minute), maxAge))
+	default:
+		report.Healthy = StatusHealthy
+	}
+
+	return report
+}
+
+// lastSuccessfulBackup returns when the newest completed backup created by the
+// schedule finished or a zero time if there isn't one.
+func lastSuccessfulBackup(schedule velerov1.Schedule, backups []velerov1.Backup) time.Time {
+	var last time.Time
+	for _, backup := range backups {
+		if backup.Namespace != schedule.Namespace || backup.Labels[velerov1.ScheduleNameLabel] != schedule.Name {
+			continue
+		}
+		if backup.Status.Phase != velerov1.BackupPhaseCompleted || backup.Status.CompletionTimestamp == nil {
+			continue
+		}
+		if backup.Status.CompletionTimestamp.After(last) {
+			last = backup.Status.CompletionTimestamp.Time
+		}
+	}
+	return last
+}
@@ -0,0 +1,113 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	. "github.com/zanloy/bms-api/models"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func genBackup(name string, schedule string, phase velerov1.BackupPhase, completed time.Time) velerov1.Backup {
+	backup := velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "velero", Name: name, Labels: map[string]string{}},
+		Status:     velerov1.BackupStatus{Phase: phase},
+	}
+	if schedule != "" {
+		backup.Labels[velerov1.ScheduleNameLabel] = schedule
+	}
+	if !completed.IsZero() {
+		backup.Status.CompletionTimestamp = &metav1.Time{Time: completed}
+	}
+	return backup
+}
+
+func TestFromVeleroBackup(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		phase   velerov1.BackupPhase
+		healthy HealthyStatus
+	}{
+		{desc: "completed", phase: velerov1.BackupPhaseCompleted, healthy: StatusHealthy},
+		{desc: "failed", phase: velerov1.BackupPhaseFailed, healthy: StatusUnhealthy},
+		{desc: "partially failed", phase: velerov1.BackupPhasePartiallyFailed, healthy: StatusUnhealthy},
+		{desc: "failed validation", phase: velerov1.BackupPhaseFailedValidation, healthy: StatusUnhealthy},
+		{desc: "in progress", phase: velerov1.BackupPhaseInProgress, healthy: StatusUnknown},
+	}
+
+	for _, tc := range testCases {
+		backup := FromVeleroBackup(genBackup("daily-1", "daily", tc.phase, time.Time{}))
+		assert.Equal(t, tc.healthy, backup.Healthy, tc.desc)
+		assert.Equal(t, "daily", backup.Schedule, tc.desc)
+		assert.Equal(t, string(tc.phase), backup.Phase, tc.desc)
+		if tc.healthy == StatusUnhealthy {
+			assert.NotEmpty(t, backup.Errors, tc.desc)
+		}
+	}
+}
+
+func TestFromVeleroSchedule(t *testing.T) {
+	now := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	maxAge := 25 * time.Hour
+
+	backups := []velerov1.Backup{
+		genBackup("daily-1", "daily", velerov1.BackupPhaseCompleted, now.Add(-36*time.Hour)),
+		genBackup("daily-2", "daily", velerov1.BackupPhaseCompleted, now.Add(-12*time.Hour)),
+		genBackup("daily-3", "daily", velerov1.BackupPhaseFailed, now.Add(-1*time.Hour)),
+		genBackup("weekly-1", "weekly", velerov1.BackupPhaseCompleted, now.Add(-72*time.Hour)),
+		genBackup("weekly-2", "weekly", velerov1.BackupPhasePartiallyFailed, now.Add(-1*time.Hour)),
+		genBackup("manual", "", velerov1.BackupPhaseCompleted, now.Add(-1*time.Hour)),
+	}
+
+	genSchedule := func(name string, created time.Time, phase velerov1.SchedulePhase) velerov1.Schedule {
+		return velerov1.Schedule{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "velero", Name: name, CreationTimestamp: metav1.Time{Time: created}},
+			Spec:       velerov1.ScheduleSpec{Schedule: "0 1 * * *"},
+			Status:     velerov1.ScheduleStatus{Phase: phase},
+		}
+	}
+
+	testCases := []struct {
+		desc     string
+		schedule velerov1.Schedule
+		healthy  HealthyStatus
+		last     time.Time
+	}{{
+		desc:     "with a recent successful backup",
+		schedule: genSchedule("daily", now.Add(-720*time.Hour), velerov1.SchedulePhaseEnabled),
+		healthy:  StatusHealthy,
+		last:     now.Add(-12 * time.Hour),
+	}, {
+		desc:     "with a stale successful backup",
+		schedule: genSchedule("weekly", now.Add(-720*time.Hour), velerov1.SchedulePhaseEnabled),
+		healthy:  StatusUnhealthy,
+		last:     now.Add(-72 * time.Hour),
+	}, {
+		desc:     "with no backups",
+		schedule: genSchedule("hourly", now.Add(-720*time.Hour), velerov1.SchedulePhaseEnabled),
+		healthy:  StatusUnhealthy,
+	}, {
+		desc:     "too new to have backups",
+		schedule: genSchedule("hourly", now.Add(-1*time.Hour), velerov1.SchedulePhaseNew),
+		healthy:  StatusUnknown,
+	}, {
+		desc:     "that failed validation",
+		schedule: genSchedule("daily", now.Add(-720*time.Hour), velerov1.SchedulePhaseFailedValidation),
+		healthy:  StatusUnhealthy,
+		last:     now.Add(-12 * time.Hour),
+	}}
+
+	for _, tc := range testCases {
+		schedule := FromVeleroSchedule(tc.schedule, backups, maxAge, now)
+		assert.Equal(t, tc.healthy, schedule.Healthy, tc.desc)
+		assert.Equal(t, tc.last, schedule.LastSuccessfulBackup, tc.desc)
+		assert.Equal(t, "0 1 * * *", schedule.Schedule, tc.desc)
+		if tc.healthy == StatusUnhealthy {
+			assert.NotEmpty(t, schedule.Errors, tc.desc)
+		} else {
+			assert.Empty(t, schedule.Errors, tc.desc)
+		}
+	}
+}
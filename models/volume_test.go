@@ -0,0 +1,156 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zanloy/bms-api/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func genClaimPod(name string, claim string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: name},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestHealthReportForPersistentVolumeClaim(t *testing.T) {
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	pods := factory.Core().V1().Pods().Informer().GetIndexer()
+	pods.Add(genClaimPod("db-1", "db-data", corev1.PodPending))
+	pods.Add(genClaimPod("db-0", "db-data", corev1.PodPending))
+	pods.Add(genClaimPod("backup-1", "backup-data", corev1.PodSucceeded))
+	agent := genClaimPod("agent-1", "agent-data", corev1.PodPending)
+	agent.Labels = map[string]string{"jenkins": "slave"}
+	pods.Add(agent)
+
+	testCases := []struct {
+		desc     string
+		name     string
+		age      time.Duration
+		phase    corev1.PersistentVolumeClaimPhase
+		expected HealthyStatus
+		errors   []string
+	}{{
+		desc:     "bound",
+		name:     "api-data",
+		phase:    corev1.ClaimBound,
+		expected: StatusHealthy,
+	}, {
+		desc:     "lost",
+		name:     "api-data",
+		phase:    corev1.ClaimLost,
+		expected: StatusUnhealthy,
+		errors:   []string{"The claim lost its volume [pv-1]."},
+	}, {
+		desc:     "pending without pods",
+		name:     "api-data",
+		phase:    corev1.ClaimPending,
+		expected: StatusWarn,
+		errors:   []string{"The claim is not bound."},
+	}, {
+		desc:     "pending with finished pods",
+		name:     "backup-data",
+		phase:    corev1.ClaimPending,
+		expected: StatusWarn,
+		errors:   []string{"The claim is not bound."},
+	}, {
+		desc:     "pending with blocked pods",
+		name:     "db-data",
+		phase:    corev1.ClaimPending,
+		expected: StatusUnhealthy,
+		errors:   []string{"Pods are blocked waiting on the claim to be bound: [db-0,db-1]."},
+	}, {
+		desc:     "new and pending with waiting pods",
+		name:     "db-data",
+		age:      time.Minute,
+		phase:    corev1.ClaimPending,
+		expected: StatusWarn,
+		errors:   []string{"Pods are waiting on the claim to be bound: [db-0,db-1]."},
+	}, {
+		desc:     "pending with ignored pods",
+		name:     "agent-data",
+		phase:    corev1.ClaimPending,
+		expected: StatusWarn,
+		errors:   []string{"The claim is not bound."},
+	}}
+
+	for _, tc := range testCases {
+		claim := corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: tc.name},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: tc.phase},
+		}
+		if tc.age > 0 {
+			claim.CreationTimestamp = metav1.NewTime(time.Now().Add(-tc.age))
+		}
+		report := HealthReportForPersistentVolumeClaim(claim, factory)
+		assert.Equal(t, "PersistentVolumeClaim", report.Kind, tc.desc)
+		assert.Equal(t, tc.expected, report.Healthy, tc.desc)
+		if len(tc.errors) > 0 {
+			assert.Equal(t, tc.errors, report.Errors, tc.desc)
+		} else {
+			assert.Empty(t, report.Errors, tc.desc)
+		}
+	}
+}
+
+func TestHealthReportForPersistentVolume(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		phase    corev1.PersistentVolumePhase
+		message  string
+		expected HealthyStatus
+		errors   []string
+	}{{
+		desc:     "available",
+		phase:    corev1.VolumeAvailable,
+		expected: StatusHealthy,
+	}, {
+		desc:     "bound",
+		phase:    corev1.VolumeBound,
+		expected: StatusHealthy,
+	}, {
+		desc:     "released",
+		phase:    corev1.VolumeReleased,
+		expected: StatusWarn,
+		errors:   []string{"The volume was released by the claim [app-dev/db-data] and has not been reclaimed."},
+	}, {
+		desc:     "failed",
+		phase:    corev1.VolumeFailed,
+		message:  "Error deleting EBS volume.",
+		expected: StatusUnhealthy,
+		errors:   []string{"The volume failed to be reclaimed: Error deleting EBS volume."},
+	}}
+
+	for _, tc := range testCases {
+		volume := corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: corev1.PersistentVolumeSpec{
+				ClaimRef: &corev1.ObjectReference{Namespace: "app-dev", Name: "db-data"},
+			},
+			Status: corev1.PersistentVolumeStatus{Phase: tc.phase, Message: tc.message},
+		}
+		report := HealthReportForPersistentVolume(volume)
+		assert.Equal(t, "PersistentVolume", report.Kind, tc.desc)
+		assert.Equal(t, tc.expected, report.Healthy, tc.desc)
+		if len(tc.errors) > 0 {
+			assert.Equal(t, tc.errors, report.Errors, tc.desc)
+		} else {
+			assert.Empty(t, report.Errors, tc.desc)
+		}
+	}
+}
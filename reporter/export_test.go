@@ -0,0 +1,21 @@
+package reporter
+
+import (
+	"time"
+
+	"github.com/zanloy/bms-api/models"
+)
+
+// Due exposes due to the reporter_test package.
+func Due(now time.Time) (time.Time, bool) {
+	return due(now)
+}
+
+// ResetScheduler forgets the loaded schedule so each test starts fresh.
+func ResetScheduler() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	schedule = nil
+	status = models.ReportScheduleStatus{}
+}
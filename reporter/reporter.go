@@ -0,0 +1,239 @@
+package reporter // import github.com/zanloy/bms-api/reporter
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zanloy/bms-api/config"
+	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/models"
+	"github.com/zanloy/bms-api/storage"
+	"github.com/zanloy/bms-api/url"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var logger = log.With().
+	Timestamp().
+	Str("component", "reporter").
+	Logger()
+
+// Generate builds a report of the current state of the cluster, or of a single
+// tenant/environment if opts is scoped. Any errors encountered along the way
+// are recorded in the report's Errors field.
+func Generate(opts models.ReportOptions) models.Report {
+	report := models.NewReport()
+	report.Tenant = opts.Tenant
+	report.Environment = opts.Environment
+
+	// Pods are needed for both the nodes and pods sections.
+	k8pods, podsErr := kubernetes.Pods("").List(labels.Everything())
+
+	// Nodes are shared by every tenant so they only go in cluster wide reports.
+	if !opts.Scoped() {
+		addNodes(&report, k8pods)
+	}
+
+	// CronJobs
+	if k8cronjobs, err := kubernetes.CronJobs("").List(labels.Everything()); err == nil {
+		for _, k8cronjob := range k8cronjobs {
+			cronjob := models.FromK8CronJob(*k8cronjob, kubernetes.Factory)
+			if !opts.Matches(cronjob.Tenant, cronjob.Environment) {
+				continue
+			}
+			report.Objects.CronJobs = append(report.Objects.CronJobs, cronjob.Namespace+"/"+cronjob.Name)
+			if cronjob.Healthy != models.StatusHealthy {
+				report.UnhealthyCronJobs = append(report.UnhealthyCronJobs, cronjob)
+			}
+		}
+	} else {
+		err = fmt.Errorf("Failed to get cronjobs from kubernetes: %w", err)
+		logAndAppendError(err, &report)
+	}
+
+	// DaemonSets
+	if k8daemonsets, err := kubernetes.DaemonSets("").List(labels.Everything()); err == nil {
+		for _, k8daemonset := range k8daemonsets {
+			daemonset := models.FromK8DaemonSet(*k8daemonset)
+			if !opts.Matches(daemonset.Tenant, daemonset.Environment) {
+				continue
+			}
+			report.Objects.DaemonSets = append(report.Objects.DaemonSets, daemonset.Namespace+"/"+daemonset.Name)
+			if daemonset.Healthy != models.StatusHealthy {
+				report.UnhealthyDaemonSets = append(report.UnhealthyDaemonSets, daemonset)
+			}
+		}
+	} else {
+		err = fmt.Errorf("Failed to get daemonsets from kubernetes: %w", err)
+		logAndAppendError(err, &report)
+	}
+
+	// Deployments
+	if k8deployments, err := kubernetes.Deployments("").List(labels.Everything()); err == nil {
+		// Iterate deployments
+		for _, k8deployment := range k8deployments {
+			deployment := models.FromK8Deployment(*k8deployment)
+			if !opts.Matches(deployment.Tenant, deployment.Environment) {
+				continue
+			}
+			report.Objects.Deployments = append(report.Objects.Deployments, deployment.Namespace+"/"+deployment.Name)
+			if deployment.Healthy != models.StatusHealthy {
+				report.UnhealthyDeployments = append(report.UnhealthyDeployments, deployment)
+			}
+		}
+	} else {
+		err = fmt.Errorf("Failed to get deployments from kubernetes: %w", err)
+		logAndAppendError(err, &report)
+	}
+
+	// Jobs
+	if k8jobs, err := kubernetes.Jobs("").List(labels.Everything()); err == nil {
+		for _, k8job := range k8jobs {
+			job := models.FromK8Job(*k8job, kubernetes.Factory)
+			if !opts.Matches(job.Tenant, job.Environment) {
+				continue
+			}
+			report.Objects.Jobs = append(report.Objects.Jobs, job.Namespace+"/"+job.Name)
+			if job.Healthy != models.StatusHealthy {
+				report.UnhealthyJobs = append(report.UnhealthyJobs, job)
+			}
+		}
+	} else {
+		err = fmt.Errorf("Failed to get jobs from kubernetes: %w", err)
+		logAndAppendError(err, &report)
+	}
+
+	// Namespaces
+	if k8namespaces, err := kubernetes.Namespaces().List(labels.Everything()); err == nil {
+		for _, k8namespace := range k8namespaces {
+			namespace := models.FromK8Namespace(k8namespace, kubernetes.Factory)
+			if !opts.Matches(namespace.Tenant, namespace.Env) {
+				continue
+			}
+			report.Objects.Namespaces = append(report.Objects.Namespaces, namespace.Name)
+			if namespace.Healthy != models.StatusHealthy {
+				report.UnhealthyNamespaces = append(report.UnhealthyNamespaces, namespace)
+			}
+		}
+	} else {
+		err = fmt.Errorf("Failed to get namespaces from kubernetes: %w", err)
+		logAndAppendError(err, &report)
+	}
+
+	// Pods and their restarts
+	if podsErr == nil {
+		threshold := config.RestartThreshold()
+		for _, k8pod := range k8pods {
+			pod := models.FromK8Pod(*k8pod)
+			if !opts.Matches(pod.Tenant, pod.Environment) || models.PodIsIgnored(*k8pod) {
+				continue
+			}
+			report.Objects.Pods = append(report.Objects.Pods, pod.Namespace+"/"+pod.Name)
+			if pod.Healthy != models.StatusHealthy {
+				report.UnhealthyPods = append(report.UnhealthyPods, pod)
+			}
+			podThreshold := threshold
+			if local, err := models.BMSConfigFor(k8pod.Namespace); err == nil && local.RestartThreshold > 0 {
+				podThreshold = local.RestartThreshold
+			}
+			report.Restarts = append(report.Restarts, models.RestartsForPod(*k8pod, podThreshold)...)
+		}
+		models.SortRestarts(report.Restarts)
+	} else {
+		err := fmt.Errorf("Failed to get pods from kubernetes: %w", podsErr)
+		logAndAppendError(err, &report)
+	}
+
+	// Services
+	if k8services, err := kubernetes.Services("").List(labels.Everything()); err == nil {
+		for _, k8service := range k8services {
+			service := models.FromK8Service(*k8service, kubernetes.Factory)
+			if !opts.Matches(service.Tenant, service.Environment) {
+				continue
+			}
+			report.Objects.Services = append(report.Objects.Services, service.Namespace+"/"+service.Name)
+			if service.Healthy != models.StatusHealthy {
+				report.UnhealthyServices = append(report.UnhealthyServices, service)
+			}
+		}
+	} else {
+		err = fmt.Errorf("Failed to get services from kubernetes: %w", err)
+		logAndAppendError(err, &report)
+	}
+
+	// StatefulSets
+	if k8statefulsets, err := kubernetes.StatefulSets("").List(labels.Everything()); err == nil {
+		for _, k8statefulset := range k8statefulsets {
+			statefulset := models.FromK8StatefulSet(*k8statefulset)
+			if !opts.Matches(statefulset.Tenant, statefulset.Environment) {
+				continue
+			}
+			report.Objects.StatefulSets = append(report.Objects.StatefulSets, statefulset.Namespace+"/"+statefulset.Name)
+			if statefulset.Healthy != models.StatusHealthy {
+				report.UnhealthyStatefulSets = append(report.UnhealthyStatefulSets, statefulset)
+			}
+		}
+	} else {
+		err = fmt.Errorf("Failed to get statefulsets from kubernetes: %w", err)
+		logAndAppendError(err, &report)
+	}
+
+	// URLs
+	for _, check := range url.GetTargets() {
+		if opts.MatchesURLCheck(check) {
+			report.URLs = append(report.URLs, check)
+		}
+	}
+
+	// Velero backs up the whole cluster so it only goes in cluster wide reports.
+	if !opts.Scoped() {
+		addVelero(&report)
+	}
+
+	report.Healthy = report.Status()
+
+	return report
+}
+
+// addNodes fills in the nodes section of the report. The pods are used to sum
+// up the resources allocated on each node.
+func addNodes(report *models.Report, k8pods []*corev1.Pod) {
+	if k8nodes, err := kubernetes.Nodes().List(labels.Everything()); err == nil {
+		// Make the nodes list the size of our nodes
+		nodelist := make([]models.Node, len(k8nodes))
+		// Iterate nodes
+		for idx, k8node := range k8nodes {
+			node := models.FromK8Node(*k8node)
+			if metrics, err := kubernetes.GetNodeMetrics(node); err == nil {
+				node.AddMetrics(metrics)
+			}
+			node.AddPods(k8pods)
+			// Add to array
+			nodelist[idx] = node
+		}
+		// Attach nodelist to report
+		report.Nodes = nodelist
+	} else {
+		err = fmt.Errorf("Failed to get nodes from kubernetes: %w", err)
+		logAndAppendError(err, report)
+	}
+}
+
+// Save will store the report and set its Key. A failure to store the report
+// is recorded in the report's Errors field and returned.
+func Save(report *models.Report) error {
+	key, err := storage.SaveReport(*report)
+	if err != nil {
+		err = fmt.Errorf("Failed to save report: %w", err)
+		logAndAppendError(err, report)
+		return err
+	}
+
+	report.Key = key
+	return nil
+}
+
+func logAndAppendError(err error, report *models.Report) {
+	logger.Err(err).Msg("An error occurred while building a report.")
+	report.Errors = append(report.Errors, err.Error())
+}
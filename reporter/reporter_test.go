@@ -0,0 +1,119 @@
+package reporter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	velerofake "github.com/vmware-tanzu/velero/pkg/generated/clientset/versioned/fake"
+	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/models"
+	"github.com/zanloy/bms-api/reporter"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func genDeployment(namespace string, name string, replicas int32, ready int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{ReadyReplicas: ready},
+	}
+}
+
+func genDaemonSet(namespace string, name string, desired int32, ready int32) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: desired, NumberReady: ready},
+	}
+}
+
+// startK8 starts the kubernetes informers against a fake clientset holding
+// objects.
+func startK8(stopCh <-chan struct{}, objects ...runtime.Object) {
+	kubernetes.InitWithClientset(fake.NewSimpleClientset(objects...))
+	kubernetes.InitWithVeleroClientset(velerofake.NewSimpleClientset())
+	kubernetes.Start(stopCh)
+}
+
+func TestGenerateWorkloads(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	startK8(stopCh,
+		genDeployment("app-dev", "api", 2, 0),
+		genDeployment("app-dev", "web", 2, 2),
+		genDeployment("other-prod", "api", 2, 0),
+		genDaemonSet("app-dev", "agent", 3, 2),
+		genDaemonSet("kube-system", "fluentd", 3, 3),
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app-dev", Name: "migrate"},
+			Status:     batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}},
+		},
+	)
+
+	report := reporter.Generate(models.ReportOptions{})
+	assert.Empty(t, report.Errors)
+
+	names := func(report models.Report) (deployments []string, daemonsets []string) {
+		deployments, daemonsets = []string{}, []string{}
+		for _, deployment := range report.UnhealthyDeployments {
+			deployments = append(deployments, deployment.Namespace+"/"+deployment.Name)
+		}
+		for _, daemonset := range report.UnhealthyDaemonSets {
+			daemonsets = append(daemonsets, daemonset.Namespace+"/"+daemonset.Name)
+		}
+		return
+	}
+
+	deployments, daemonsets := names(report)
+	assert.ElementsMatch(t, []string{"app-dev/api", "other-prod/api"}, deployments, "unhealthy deployments")
+	assert.ElementsMatch(t, []string{"app-dev/agent"}, daemonsets, "unhealthy daemonsets")
+	assert.ElementsMatch(t, []string{"app-dev/api", "app-dev/web", "other-prod/api"}, report.Objects.Deployments, "all deployments")
+	if assert.Len(t, report.UnhealthyJobs, 1, "unhealthy jobs") {
+		assert.Equal(t, "migrate", report.UnhealthyJobs[0].Name)
+	}
+	assert.Equal(t, models.StatusUnhealthy, report.Healthy, "report health")
+	assert.Equal(t, 2, report.Summary().Counts["unhealthy_deployments"])
+	assert.Equal(t, 1, report.Summary().Counts["warn_daemonsets"], "the agent daemonset is degraded")
+	assert.Equal(t, 0, report.Summary().Counts["unhealthy_daemonsets"])
+
+	// Scoped to a tenant
+	report = reporter.Generate(models.ReportOptions{Tenant: "app", Environment: "dev"})
+	assert.Equal(t, "app", report.Tenant)
+	deployments, daemonsets = names(report)
+	assert.ElementsMatch(t, []string{"app-dev/api"}, deployments, "unhealthy deployments for tenant")
+	assert.ElementsMatch(t, []string{"app-dev/agent"}, daemonsets, "unhealthy daemonsets for tenant")
+	assert.ElementsMatch(t, []string{"app-dev/api", "app-dev/web"}, report.Objects.Deployments, "all deployments for tenant")
+	assert.Empty(t, report.Nodes, "nodes are left out of tenant reports")
+}
+
+func TestGenerateRestarts(t *testing.T) {
+	genRestartingPod := func(namespace string, restarts int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "api-1"},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "api", RestartCount: restarts}},
+			},
+		}
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	startK8(stopCh,
+		genRestartingPod("app-dev", 8),
+		genRestartingPod("batch-dev", 8),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "batch-dev", Name: models.BMSConfigMapName},
+			Data:       map[string]string{models.BMSConfigMapKey: "restart_threshold: 10\n"},
+		},
+	)
+
+	report := reporter.Generate(models.ReportOptions{})
+	if assert.Len(t, report.Restarts, 1, "batch-dev allows more restarts") {
+		assert.Equal(t, "app-dev", report.Restarts[0].Namespace)
+	}
+}
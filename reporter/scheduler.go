@@ -0,0 +1,105 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/zanloy/bms-api/config"
+	"github.com/zanloy/bms-api/models"
+	"github.com/zanloy/bms-api/storage"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var (
+	mutex    = sync.Mutex{}
+	schedule cron.Schedule
+	status   = models.ReportScheduleStatus{}
+)
+
+// Start will generate and save reports on the cron schedule set by
+// report_schedule in the config until told to stop via the stopCh channel.
+// The schedule is re-read from the config on every tick so config reloads
+// take effect without a restart.
+func Start(stopCh <-chan struct{}) {
+	logger.Info().Msg("Starting report scheduler.")
+
+	go wait.Until(tick, 10*time.Second, stopCh)
+
+	<-stopCh
+	logger.Info().Msg("Stopping report scheduler.")
+}
+
+// Status returns the current state of the report scheduler.
+func Status() models.ReportScheduleStatus {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return status
+}
+
+// tick will (re)load the schedule if it changed and run it if it is due.
+func tick() {
+	slot, ok := due(time.Now())
+	if !ok {
+		return
+	}
+
+	report := Generate(models.ReportOptions{})
+	report.Scheduled = slot
+	key, err := storage.SaveReport(report)
+	if errors.Is(err, storage.ScheduledReportExistsError) {
+		// Every replica runs the schedule and another one beat us to it.
+		logger.Debug().Str("key", key).Msg("Scheduled report was already saved.")
+		err = nil
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	status.LastRun = report.Date
+	if err == nil {
+		status.LastKey = key
+		status.LastError = ""
+		logger.Info().Str("key", key).Msg("Scheduled report saved.")
+	} else {
+		status.LastError = err.Error()
+		logger.Err(err).Msg("Failed to save scheduled report.")
+	}
+}
+
+// due loads the schedule from config if it changed and returns the run that
+// is due at now, if any. When a run is due the next run is moved forward.
+func due(now time.Time) (time.Time, bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if spec := config.ReportSchedule(); spec != status.Schedule {
+		status.Schedule = spec
+		status.NextRun = time.Time{}
+		schedule = nil
+
+		if spec != "" {
+			parsed, err := cron.ParseStandard(spec)
+			if err != nil {
+				status.LastError = fmt.Sprintf("Failed to parse report_schedule [%s]: %s", spec, err.Error())
+				logger.Error().Str("schedule", spec).Err(err).Msg("Failed to parse report_schedule.")
+				return time.Time{}, false
+			}
+			schedule = parsed
+			status.LastError = ""
+			status.NextRun = schedule.Next(now)
+			logger.Info().Str("schedule", spec).Msg(fmt.Sprintf("Reports scheduled, next run at %s.", status.NextRun.Format(time.RFC3339)))
+		}
+	}
+
+	if schedule == nil || now.Before(status.NextRun) {
+		return time.Time{}, false
+	}
+
+	slot := status.NextRun
+	status.NextRun = schedule.Next(now)
+	return slot, true
+}
@@ -0,0 +1,90 @@
+package reporter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zanloy/bms-api/config"
+	"github.com/zanloy/bms-api/reporter"
+)
+
+func TestDue(t *testing.T) {
+	reporter.ResetScheduler()
+	defer reporter.ResetScheduler()
+	defer func() { config.Config.ReportSchedule = "" }()
+
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2020, 9, 13, hour, minute, 0, 0, time.UTC)
+	}
+
+	// Each step runs against the state left by the one before it.
+	testCases := []struct {
+		desc      string
+		schedule  string
+		now       time.Time
+		due       bool
+		slot      time.Time
+		nextRun   time.Time
+		lastError string
+	}{{
+		desc:     "first load",
+		schedule: "0 * * * *",
+		now:      at(10, 30),
+		nextRun:  at(11, 0),
+	}, {
+		desc:     "not yet due",
+		schedule: "0 * * * *",
+		now:      at(10, 59),
+		nextRun:  at(11, 0),
+	}, {
+		desc:     "due",
+		schedule: "0 * * * *",
+		now:      at(11, 0),
+		due:      true,
+		slot:     at(11, 0),
+		nextRun:  at(12, 0),
+	}, {
+		desc:     "late but due",
+		schedule: "0 * * * *",
+		now:      at(12, 0).Add(9 * time.Second),
+		due:      true,
+		slot:     at(12, 0),
+		nextRun:  at(13, 0),
+	}, {
+		desc:     "changed spec",
+		schedule: "*/15 * * * *",
+		now:      at(12, 5),
+		nextRun:  at(12, 15),
+	}, {
+		desc:      "invalid spec",
+		schedule:  "every tuesday",
+		now:       at(12, 15),
+		lastError: "Failed to parse report_schedule [every tuesday]",
+	}, {
+		desc:     "fixed spec",
+		schedule: "*/15 * * * *",
+		now:      at(12, 20),
+		nextRun:  at(12, 30),
+	}, {
+		desc:     "disabled",
+		schedule: "",
+		now:      at(12, 30),
+	}}
+
+	for _, tc := range testCases {
+		config.Config.ReportSchedule = tc.schedule
+		slot, due := reporter.Due(tc.now)
+		assert.Equal(t, tc.due, due, tc.desc)
+		assert.True(t, tc.slot.Equal(slot), "%s: expected slot %s but got %s", tc.desc, tc.slot, slot)
+
+		status := reporter.Status()
+		assert.Equal(t, tc.schedule, status.Schedule, tc.desc)
+		assert.True(t, tc.nextRun.Equal(status.NextRun), "%s: expected next run %s but got %s", tc.desc, tc.nextRun, status.NextRun)
+		if tc.lastError != "" {
+			assert.Contains(t, status.LastError, tc.lastError, tc.desc)
+		} else {
+			assert.Empty(t, status.LastError, tc.desc)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package reporter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zanloy/bms-api/config"
+	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/models"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// VeleroHealth returns the health of every velero backup and schedule in the
+// velero namespace. If velero isn't installed the error is a NotFound.
+func VeleroHealth() ([]models.VeleroBackup, []models.VeleroSchedule, error) {
+	namespace := config.VeleroNamespace()
+
+	k8backups, err := kubernetes.VeleroBackups(namespace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get velero backups from kubernetes: %w", err)
+	}
+
+	k8schedules, err := kubernetes.VeleroSchedules(namespace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get velero schedules from kubernetes: %w", err)
+	}
+
+	backups := make([]models.VeleroBackup, len(k8backups.Items))
+	for idx, k8backup := range k8backups.Items {
+		backups[idx] = models.FromVeleroBackup(k8backup)
+	}
+
+	maxAge := config.VeleroMaxBackupAge()
+	now := time.Now()
+	schedules := make([]models.VeleroSchedule, len(k8schedules.Items))
+	for idx, k8schedule := range k8schedules.Items {
+		schedules[idx] = models.FromVeleroSchedule(k8schedule, k8backups.Items, maxAge, now)
+	}
+
+	return backups, schedules, nil
+}
+
+// addVelero fills in the velero section of the report. Clusters without
+// velero installed get an empty section rather than an error.
+func addVelero(report *models.Report) {
+	backups, schedules, err := VeleroHealth()
+	if err != nil {
+		if k8errors.IsNotFound(err) {
+			logger.Debug().Err(err).Msg("Velero does not appear to be installed. Skipping velero section of report.")
+		} else {
+			logAndAppendError(err, report)
+		}
+		return
+	}
+
+	// Velero keeps failed backups until they expire so only the recent ones
+	// are worth reporting.
+	cutoff := report.Date.Add(-config.VeleroMaxBackupAge())
+	for _, backup := range backups {
+		if backup.Healthy == models.StatusUnhealthy && !backup.Created.Before(cutoff) {
+			report.Velero.FailedBackups = append(report.Velero.FailedBackups, backup)
+		}
+	}
+
+	for _, schedule := range schedules {
+		if schedule.Healthy == models.StatusUnhealthy {
+			report.Velero.UnhealthySchedules = append(report.Velero.UnhealthySchedules, schedule)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package reporter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	velerofake "github.com/vmware-tanzu/velero/pkg/generated/clientset/versioned/fake"
+	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/models"
+	"github.com/zanloy/bms-api/reporter"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func genVeleroBackup(name string, schedule string, phase velerov1.BackupPhase, age time.Duration) *velerov1.Backup {
+	completed := metav1.NewTime(time.Now().Add(-age))
+	return &velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "velero",
+			Name:              name,
+			Labels:            map[string]string{velerov1.ScheduleNameLabel: schedule},
+			CreationTimestamp: completed,
+		},
+		Status: velerov1.BackupStatus{Phase: phase, CompletionTimestamp: &completed},
+	}
+}
+
+func genVeleroSchedule(name string) *velerov1.Schedule {
+	return &velerov1.Schedule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "velero", Name: name, CreationTimestamp: metav1.NewTime(time.Now().Add(-30 * 24 * time.Hour))},
+		Spec:       velerov1.ScheduleSpec{Schedule: "0 1 * * *"},
+		Status:     velerov1.ScheduleStatus{Phase: velerov1.SchedulePhaseEnabled},
+	}
+}
+
+func TestVelero(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	kubernetes.InitWithClientset(fake.NewSimpleClientset())
+	kubernetes.InitWithVeleroClientset(velerofake.NewSimpleClientset(
+		genVeleroBackup("daily-1", "daily", velerov1.BackupPhaseFailed, 72*time.Hour),
+		genVeleroBackup("daily-2", "daily", velerov1.BackupPhaseCompleted, 2*time.Hour),
+		genVeleroBackup("daily-3", "daily", velerov1.BackupPhaseFailed, time.Hour),
+		genVeleroBackup("weekly-1", "weekly", velerov1.BackupPhaseCompleted, 72*time.Hour),
+		genVeleroSchedule("daily"),
+		genVeleroSchedule("weekly"),
+	))
+	kubernetes.Start(stopCh)
+
+	backups, schedules, err := reporter.VeleroHealth()
+	if assert.NoError(t, err) {
+		assert.Len(t, backups, 4)
+		assert.Len(t, schedules, 2)
+	}
+
+	report := reporter.Generate(models.ReportOptions{})
+	assert.Empty(t, report.Errors)
+
+	failed := make([]string, len(report.Velero.FailedBackups))
+	for idx, backup := range report.Velero.FailedBackups {
+		failed[idx] = backup.Name
+	}
+	assert.Equal(t, []string{"daily-3"}, failed, "only failed backups within max_backup_age are reported")
+
+	unhealthy := make([]string, len(report.Velero.UnhealthySchedules))
+	for idx, schedule := range report.Velero.UnhealthySchedules {
+		unhealthy[idx] = schedule.Name
+	}
+	assert.Equal(t, []string{"weekly"}, unhealthy, "schedules without a backup within max_backup_age are reported")
+	if assert.Len(t, report.Velero.UnhealthySchedules, 1) {
+		assert.NotEmpty(t, report.Velero.UnhealthySchedules[0].Errors)
+	}
+
+	// Scoped reports leave velero out.
+	report = reporter.Generate(models.ReportOptions{Tenant: "app"})
+	assert.Empty(t, report.Velero.FailedBackups)
+	assert.Empty(t, report.Velero.UnhealthySchedules)
+}
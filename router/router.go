@@ -34,8 +34,12 @@ func SetupRouter() *gin.Engine {
 		namespaceCtl = new(controllers.NamespaceController)
 		nodeCtl      = new(controllers.NodeController)
 		podCtl       = new(controllers.PodController)
+		pvcCtl       = new(controllers.PersistentVolumeClaimController)
+		pvCtl        = new(controllers.PersistentVolumeController)
 		reportCtl    = new(controllers.ReportController)
+		serviceCtl   = new(controllers.ServiceController)
 		urlCtl       = new(controllers.URLController)
+		veleroCtl    = new(controllers.VeleroController)
 	)
 
 	/* Setup routes */
@@ -47,12 +51,20 @@ func SetupRouter() *gin.Engine {
 
 	healthGrp := router.Group("/health")
 	{
+		healthGrp.GET("/backups", veleroCtl.GetBackups)
 		healthGrp.GET("/namespaces", namespaceCtl.GetAllHealth)
 		healthGrp.GET("/namespaces/ws", namespaceCtl.WatchHealth)
 		healthGrp.GET("/nodes", nodeCtl.GetAllHealth)
 		healthGrp.GET("/nodes/ws", nodeCtl.WatchHealth)
 		healthGrp.GET("/pods", podCtl.GetAllHealth)
 		healthGrp.GET("/pods/ws", podCtl.WatchHealth)
+		healthGrp.GET("/pvcs", pvcCtl.GetAllHealth)
+		healthGrp.GET("/pvcs/ws", pvcCtl.WatchHealth)
+		healthGrp.GET("/pvs", pvCtl.GetAllHealth)
+		healthGrp.GET("/pvs/ws", pvCtl.WatchHealth)
+		healthGrp.GET("/schedules", veleroCtl.GetSchedules)
+		healthGrp.GET("/services", serviceCtl.GetAllHealth)
+		healthGrp.GET("/services/ws", serviceCtl.WatchHealth)
 		healthGrp.GET("/urls", urlCtl.GetAll)
 		healthGrp.GET("/urls/ws", urlCtl.WatchHealth)
 		// This endpoint has no filter and will notify on all health updates
@@ -70,7 +82,13 @@ func SetupRouter() *gin.Engine {
 	router.GET("/report", reportCtl.Create)
 	reportsGrp := router.Group("/reports")
 	{
+		reportsGrp.GET("", reportCtl.List)
 		reportsGrp.GET("/create", reportCtl.Create)
+		reportsGrp.GET("/diff", reportCtl.Diff)
+		reportsGrp.GET("/schedule", reportCtl.Schedule)
+		reportsGrp.GET("/trend", reportCtl.Trend)
+		reportsGrp.GET("/:timestamp", reportCtl.Get)
+		reportsGrp.DELETE("/:timestamp", reportCtl.Delete)
 	}
 
 	logger.Debug().Msg("Router successfully initialized.")
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/zanloy/bms-api/models"
+)
+
+const reportsFilename = "reports.json.gz"
+
+// fileBackend keeps all reports gzipped in a single file inside a directory.
+// It is meant for a persistent volume mounted into a single replica.
+type fileBackend struct {
+	path string
+}
+
+func newFileBackend(dir string) (*fileBackend, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("storage.path must be set to use the filesystem storage backend.")
+	}
+	return &fileBackend{path: filepath.Join(dir, reportsFilename)}, nil
+}
+
+func (fb *fileBackend) Load() ([]models.Report, error) {
+	data, err := ioutil.ReadFile(fb.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Nothing has been saved yet.
+			return []models.Report{}, nil
+		}
+		return []models.Report{}, err
+	}
+
+	reports, err := decodeReports(data)
+	if err != nil {
+		return []models.Report{}, fmt.Errorf("Failed to decode reports from file [%s]: %w", fb.path, err)
+	}
+
+	return reports, nil
+}
+
+func (fb *fileBackend) Update(fn func([]models.Report) ([]models.Report, error)) error {
+	reports, err := fb.Load()
+	if err != nil {
+		return err
+	}
+
+	if reports, err = fn(reports); err != nil {
+		return err
+	}
+
+	reportBytes, err := encodeReports(reports)
+	if err != nil {
+		return fmt.Errorf("Error while trying to encode reports: %w", err)
+	}
+
+	// Write to a temp file and rename it over the old one so a crash never
+	// leaves a half written file behind.
+	dir := filepath.Dir(fb.path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("Failed to create directory [%s]: %w", dir, err)
+	}
+
+	tmp, err := ioutil.TempFile(dir, reportsFilename+".*")
+	if err != nil {
+		return fmt.Errorf("Failed to create temp file in [%s]: %w", dir, err)
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed.
+
+	if _, err := tmp.Write(reportBytes); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to write reports to [%s]: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to write reports to [%s]: %w", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), fb.path); err != nil {
+		return fmt.Errorf("Failed to move reports into [%s]: %w", fb.path, err)
+	}
+
+	return nil
+}
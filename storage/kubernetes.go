@@ -0,0 +1,329 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/models"
+)
+
+// Kubernetes refuses Secrets and ConfigMaps over 1MiB so the gzipped reports
+// are split across chunk objects of at most maxChunkBytes each.
+const (
+	maxChunkBytes    = 900 * 1024
+	defaultMaxChunks = 10
+)
+
+// staleIndexError means a chunk listed in the index was removed by another
+// writer while we were reading. Reading again will pick up the new index.
+var staleIndexError = fmt.Errorf("index changed while reading")
+
+// updateBackoff is how long we keep retrying when another replica beats us to
+// writing the index.
+var updateBackoff = wait.Backoff{
+	Steps:    10,
+	Duration: 10 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.5,
+	Cap:      2 * time.Second,
+}
+
+// kubeObjects is the small part of a Secret or ConfigMap client we need to keep
+// reports in kubernetes.
+type kubeObjects interface {
+	kind() string
+	// get returns the data and resourceVersion of the object.
+	get(ctx context.Context, name string) (map[string][]byte, string, error)
+	create(ctx context.Context, name string, data map[string][]byte) error
+	// update will fail with a Conflict if the object is no longer at
+	// resourceVersion.
+	update(ctx context.Context, name string, data map[string][]byte, resourceVersion string) error
+	delete(ctx context.Context, name string) error
+}
+
+// kubeIndex is stored in the 'index' key of the index object and lists the
+// chunk objects that, concatenated in order, hold the gzipped reports.
+type kubeIndex struct {
+	Chunks []string `json:"chunks"`
+
+	resourceVersion string // Of the index object we read.
+}
+
+// kubeBackend keeps reports gzipped in a set of chunk objects that are tracked
+// by an index object called name. Every write creates a fresh set of chunks
+// (name-<random>-0, name-<random>-1, ...) and then swaps the index over to them
+// using its resourceVersion, so replicas racing each other retry instead of
+// overwriting each other's reports.
+type kubeBackend struct {
+	objects   kubeObjects
+	name      string
+	maxChunks int
+}
+
+func (kb *kubeBackend) Load() ([]models.Report, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var blob []byte
+
+	err := retry.OnError(updateBackoff, isStaleIndex, func() (err error) {
+		blob, _, err = kb.read(ctx)
+		return
+	})
+	if err != nil {
+		if k8errors.IsNotFound(err) {
+			// Nothing has been saved yet.
+			return []models.Report{}, nil
+		}
+		return []models.Report{}, err
+	}
+
+	return kb.decode(blob)
+}
+
+func (kb *kubeBackend) Update(fn func([]models.Report) ([]models.Report, error)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	return retry.OnError(updateBackoff, isRetryable, func() error {
+		return kb.update(ctx, fn)
+	})
+}
+
+// update is a single attempt at a read-modify-write of the reports. It will
+// return a Conflict or AlreadyExists error if another writer got there first.
+func (kb *kubeBackend) update(ctx context.Context, fn func([]models.Report) ([]models.Report, error)) error {
+	exists := true
+	reports := []models.Report{}
+
+	blob, prevIndex, err := kb.read(ctx)
+	if err == nil {
+		if reports, err = kb.decode(blob); err != nil {
+			return err
+		}
+	} else if k8errors.IsNotFound(err) {
+		exists = false
+	} else {
+		return fmt.Errorf("Failed to pull reports from kubernetes: %w", err)
+	}
+
+	if reports, err = fn(reports); err != nil {
+		return err
+	}
+
+	blob, err = encodeReports(reports)
+	if err != nil {
+		// This should never happen. Good luck getting 100% code coverage.
+		return fmt.Errorf("Error while trying to encode reports: %w", err)
+	}
+
+	// Make sure we fit before we write anything.
+	chunks := splitChunks(blob, maxChunkBytes)
+	if len(chunks) > kb.maxChunks {
+		return fmt.Errorf("Refusing to save %d reports: they are %d bytes compressed which needs %d %ss of %d bytes but storage.max_chunks is %d. Lower max_reports or raise storage.max_chunks.", len(reports), len(blob), len(chunks), kb.objects.kind(), maxChunkBytes, kb.maxChunks)
+	}
+
+	// Write the chunks first so the index never points at missing data.
+	suffix := utilrand.String(5)
+	index := kubeIndex{Chunks: make([]string, 0, len(chunks))}
+	for idx, chunk := range chunks {
+		name := fmt.Sprintf("%s-%s-%d", kb.name, suffix, idx)
+		if err := kb.objects.create(ctx, name, map[string][]byte{"reports": chunk}); err != nil {
+			kb.deleteChunks(ctx, index.Chunks)
+			return fmt.Errorf("Error while trying to create %s [%s]: %w", kb.objects.kind(), name, err)
+		}
+		index.Chunks = append(index.Chunks, name)
+	}
+
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		kb.deleteChunks(ctx, index.Chunks)
+		return fmt.Errorf("Error while trying to encode index: %w", err)
+	}
+	data := map[string][]byte{"index": indexBytes}
+
+	// Swap the index over to our chunks. This is where we find out if someone
+	// else wrote since we read.
+	if exists {
+		err = kb.objects.update(ctx, kb.name, data, prevIndex.resourceVersion)
+	} else {
+		err = kb.objects.create(ctx, kb.name, data)
+	}
+	if err != nil {
+		kb.deleteChunks(ctx, index.Chunks)
+		return fmt.Errorf("Error while trying to save %s [%s]: %w", kb.objects.kind(), kb.name, err)
+	}
+
+	// Clean up the chunks we replaced.
+	kb.deleteChunks(ctx, prevIndex.Chunks)
+
+	return nil
+}
+
+func (kb *kubeBackend) decode(blob []byte) ([]models.Report, error) {
+	reports, err := decodeReports(blob)
+	if err != nil {
+		return []models.Report{}, fmt.Errorf("Failed to decode reports from %s [%s]: %w", kb.objects.kind(), kb.name, err)
+	}
+
+	return reports, nil
+}
+
+// deleteChunks removes chunk objects, logging any failures.
+func (kb *kubeBackend) deleteChunks(ctx context.Context, names []string) {
+	for _, name := range names {
+		if err := kb.objects.delete(ctx, name); err != nil && !k8errors.IsNotFound(err) {
+			logger.Warn().Err(err).Str("name", name).Msg(fmt.Sprintf("Failed to delete unused %s.", kb.objects.kind()))
+		}
+	}
+}
+
+// read returns the gzipped reports and the index they were read with. If the
+// index object doesn't exist the NotFound error is returned as is.
+func (kb *kubeBackend) read(ctx context.Context) ([]byte, kubeIndex, error) {
+	var index kubeIndex
+
+	data, resourceVersion, err := kb.objects.get(ctx, kb.name)
+	if err != nil {
+		return nil, index, err
+	}
+
+	b, ok := data["index"]
+	if !ok {
+		// Reports saved before chunking live in the index object itself.
+		if b, ok := data["reports"]; ok {
+			index.resourceVersion = resourceVersion
+			return b, index, nil
+		}
+		return nil, index, fmt.Errorf("No 'index' field in %s [%s].", kb.objects.kind(), kb.name)
+	}
+
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, index, fmt.Errorf("Failed to parse index in %s [%s]: %w", kb.objects.kind(), kb.name, err)
+	}
+	index.resourceVersion = resourceVersion
+
+	var blob bytes.Buffer
+	for _, name := range index.Chunks {
+		chunk, _, err := kb.objects.get(ctx, name)
+		if err != nil {
+			if k8errors.IsNotFound(err) {
+				// Another writer replaced the index after we read it.
+				return nil, index, fmt.Errorf("Failed to get chunk %s [%s]: %w", kb.objects.kind(), name, staleIndexError)
+			}
+			return nil, index, fmt.Errorf("Failed to get chunk %s [%s]: %s", kb.objects.kind(), name, err.Error())
+		}
+		b, ok := chunk["reports"]
+		if !ok {
+			return nil, index, fmt.Errorf("No 'reports' field in %s [%s].", kb.objects.kind(), name)
+		}
+		blob.Write(b)
+	}
+
+	return blob.Bytes(), index, nil
+}
+
+// isRetryable returns true for errors caused by another writer.
+func isRetryable(err error) bool {
+	return k8errors.IsConflict(err) || k8errors.IsAlreadyExists(err) || isStaleIndex(err)
+}
+
+func isStaleIndex(err error) bool {
+	return errors.Is(err, staleIndexError)
+}
+
+// splitChunks splits data into chunks of at most size bytes.
+func splitChunks(data []byte, size int) [][]byte {
+	chunks := make([][]byte, 0, len(data)/size+1)
+	for len(data) > size {
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return append(chunks, data)
+}
+
+// secretObjects stores reports in Secrets.
+type secretObjects struct {
+	namespace string
+}
+
+func (so secretObjects) kind() string { return "secret" }
+
+func (so secretObjects) get(ctx context.Context, name string) (map[string][]byte, string, error) {
+	secret, err := kubernetes.Clientset.CoreV1().Secrets(so.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	return secret.Data, secret.ResourceVersion, nil
+}
+
+func (so secretObjects) create(ctx context.Context, name string, data map[string][]byte) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Data:       data,
+	}
+	_, err := kubernetes.Clientset.CoreV1().Secrets(so.namespace).Create(ctx, secret, metav1.CreateOptions{})
+	return err
+}
+
+func (so secretObjects) update(ctx context.Context, name string, data map[string][]byte, resourceVersion string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: resourceVersion},
+		Data:       data,
+	}
+	_, err := kubernetes.Clientset.CoreV1().Secrets(so.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+func (so secretObjects) delete(ctx context.Context, name string) error {
+	return kubernetes.Clientset.CoreV1().Secrets(so.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// configMapObjects stores reports in the BinaryData of ConfigMaps.
+type configMapObjects struct {
+	namespace string
+}
+
+func (co configMapObjects) kind() string { return "configmap" }
+
+func (co configMapObjects) get(ctx context.Context, name string) (map[string][]byte, string, error) {
+	configmap, err := kubernetes.Clientset.CoreV1().ConfigMaps(co.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	return configmap.BinaryData, configmap.ResourceVersion, nil
+}
+
+func (co configMapObjects) create(ctx context.Context, name string, data map[string][]byte) error {
+	configmap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		BinaryData: data,
+	}
+	_, err := kubernetes.Clientset.CoreV1().ConfigMaps(co.namespace).Create(ctx, configmap, metav1.CreateOptions{})
+	return err
+}
+
+func (co configMapObjects) update(ctx context.Context, name string, data map[string][]byte, resourceVersion string) error {
+	configmap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: resourceVersion},
+		BinaryData: data,
+	}
+	_, err := kubernetes.Clientset.CoreV1().ConfigMaps(co.namespace).Update(ctx, configmap, metav1.UpdateOptions{})
+	return err
+}
+
+func (co configMapObjects) delete(ctx context.Context, name string) error {
+	return kubernetes.Clientset.CoreV1().ConfigMaps(co.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
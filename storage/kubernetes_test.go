@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/models"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// enforceResourceVersions makes the fake clientset behave like the API server
+// and reject updates made against a stale resourceVersion.
+func enforceResourceVersions(clientset *fake.Clientset) {
+	var version int64
+
+	clientset.PrependReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		meta, err := apimeta.Accessor(action.(k8stesting.CreateAction).GetObject())
+		if err != nil {
+			return true, nil, err
+		}
+		version++
+		meta.SetResourceVersion(strconv.FormatInt(version, 10))
+		return false, nil, nil // Let the tracker store it.
+	})
+
+	clientset.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		update := action.(k8stesting.UpdateAction)
+		meta, err := apimeta.Accessor(update.GetObject())
+		if err != nil {
+			return true, nil, err
+		}
+		existing, err := clientset.Tracker().Get(update.GetResource(), update.GetNamespace(), meta.GetName())
+		if err != nil {
+			return false, nil, nil // Let the tracker return NotFound.
+		}
+		existingMeta, _ := apimeta.Accessor(existing)
+		if meta.GetResourceVersion() != "" && meta.GetResourceVersion() != existingMeta.GetResourceVersion() {
+			return true, nil, k8errors.NewConflict(update.GetResource().GroupResource(), meta.GetName(), fmt.Errorf("the object has been modified"))
+		}
+		version++
+		meta.SetResourceVersion(strconv.FormatInt(version, 10))
+		return false, nil, nil
+	})
+}
+
+func genReport(timestamp int64) models.Report {
+	report := models.NewReport()
+	report.Date = time.Unix(timestamp, 0)
+	return report
+}
+
+func appendReport(report models.Report) func([]models.Report) ([]models.Report, error) {
+	return func(reports []models.Report) ([]models.Report, error) {
+		return append(reports, report), nil
+	}
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	cases := []struct {
+		desc    string
+		objects kubeObjects
+		seeded  bool // Racing to update an existing index instead of creating it.
+	}{
+		{desc: "secret create", objects: secretObjects{namespace: "bms"}},
+		{desc: "secret update", objects: secretObjects{namespace: "bms"}, seeded: true},
+		{desc: "configmap create", objects: configMapObjects{namespace: "bms"}},
+		{desc: "configmap update", objects: configMapObjects{namespace: "bms"}, seeded: true},
+	}
+
+	for _, testcase := range cases {
+		objects := testcase.objects
+		clientset := fake.NewSimpleClientset()
+		enforceResourceVersions(clientset)
+		kubernetes.InitWithClientset(clientset)
+
+		expected := 0
+		if testcase.seeded {
+			backend := &kubeBackend{objects: objects, name: SecretName, maxChunks: defaultMaxChunks}
+			assert.NoError(t, backend.Update(appendReport(genReport(1500000000))), testcase.desc)
+			expected++
+		}
+
+		// Every writer is its own backend without the package mutex, just like
+		// separate replicas.
+		const writers = 6
+		expected += writers
+		wg := sync.WaitGroup{}
+		wg.Add(writers)
+		for idx := 0; idx < writers; idx++ {
+			go func(idx int) {
+				defer wg.Done()
+				backend := &kubeBackend{objects: objects, name: SecretName, maxChunks: defaultMaxChunks}
+				err := backend.Update(appendReport(genReport(int64(1600000000 + idx))))
+				assert.NoError(t, err, "writer %d with %s", idx, testcase.desc)
+			}(idx)
+		}
+		wg.Wait()
+
+		backend := &kubeBackend{objects: objects, name: SecretName, maxChunks: defaultMaxChunks}
+		reports, err := backend.Load()
+		assert.NoError(t, err, "loading after concurrent writes with %s", testcase.desc)
+		assert.Len(t, reports, expected, "no writer should be lost with %s", testcase.desc)
+
+		// Losing writers must clean up their chunks.
+		assert.Equal(t, 2, countObjects(t, clientset, objects.kind()), "only the index and one chunk should be left with %s", testcase.desc)
+	}
+}
+
+func TestUpdateRetriesOnConflict(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	enforceResourceVersions(clientset)
+	kubernetes.InitWithClientset(clientset)
+
+	backend := &kubeBackend{objects: secretObjects{namespace: "bms"}, name: SecretName, maxChunks: defaultMaxChunks}
+	other := &kubeBackend{objects: secretObjects{namespace: "bms"}, name: SecretName, maxChunks: defaultMaxChunks}
+
+	assert.NoError(t, backend.Update(appendReport(genReport(1500000000))))
+
+	// Another replica writes between our read and our write on the first try.
+	calls := 0
+	err := backend.Update(func(reports []models.Report) ([]models.Report, error) {
+		calls++
+		if calls == 1 {
+			assert.NoError(t, other.Update(appendReport(genReport(1600000000))))
+		}
+		return append(reports, genReport(1600003600)), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls, "update should have been retried once")
+
+	reports, err := backend.Load()
+	assert.NoError(t, err)
+	assert.Len(t, reports, 3, "every report should be kept")
+
+	// Only the index and the chunk it points at should be left.
+	assert.Equal(t, 2, countObjects(t, clientset, "secret"), "stale chunks should be cleaned up")
+}
+
+func countObjects(t *testing.T, clientset *fake.Clientset, kind string) int {
+	ctx := context.Background()
+	if kind == "secret" {
+		list, err := clientset.CoreV1().Secrets("bms").List(ctx, metav1.ListOptions{})
+		assert.NoError(t, err)
+		return len(list.Items)
+	}
+	list, err := clientset.CoreV1().ConfigMaps("bms").List(ctx, metav1.ListOptions{})
+	assert.NoError(t, err)
+	return len(list.Items)
+}
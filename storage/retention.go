@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/zanloy/bms-api/models"
+)
+
+// retentionTier is a parsed models.RetentionTier.
+type retentionTier struct {
+	within time.Duration
+	every  time.Duration
+}
+
+// parseRetention validates the retention tiers from the config and returns
+// them sorted from shortest to longest.
+func parseRetention(input []models.RetentionTier) ([]retentionTier, error) {
+	tiers := make([]retentionTier, len(input))
+	for idx, tier := range input {
+		within, every, err := tier.Durations()
+		if err != nil {
+			return nil, err
+		}
+		tiers[idx] = retentionTier{within: within, every: every}
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].within < tiers[j].within })
+
+	return tiers, nil
+}
+
+// applyRetention returns the reports that should be kept at now. A report
+// falls in the shortest tier whose within covers its age and, if that tier has
+// an every, only the oldest report in each every sized slot of time is kept.
+// Reports older than every tier are dropped.
+func applyRetention(reports []models.Report, tiers []retentionTier, now time.Time) []models.Report {
+	sorted := make([]models.Report, len(reports))
+	copy(sorted, reports)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	type slot struct {
+		tier  int
+		start int64
+	}
+	seen := map[slot]bool{}
+
+	kept := make([]models.Report, 0, len(sorted))
+	for _, report := range sorted {
+		age := now.Sub(report.Date)
+
+		tierIdx := -1
+		for idx, tier := range tiers {
+			if age <= tier.within {
+				tierIdx = idx
+				break
+			}
+		}
+		if tierIdx == -1 {
+			continue // Older than we keep anything.
+		}
+
+		if every := tiers[tierIdx].every; every > 0 {
+			key := slot{tier: tierIdx, start: report.Date.Truncate(every).Unix()}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+
+		kept = append(kept, report)
+	}
+
+	return kept
+}
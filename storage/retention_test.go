@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zanloy/bms-api/models"
+)
+
+func TestParseRetention(t *testing.T) {
+	tiers, err := parseRetention([]models.RetentionTier{
+		{Within: "52w", Every: "1w"},
+		{Within: "48h"},
+		{Within: "30d", Every: "1d"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []retentionTier{
+		{within: 48 * time.Hour},
+		{within: 30 * 24 * time.Hour, every: 24 * time.Hour},
+		{within: 52 * 7 * 24 * time.Hour, every: 7 * 24 * time.Hour},
+	}, tiers, "tiers should be sorted shortest first")
+
+	_, err = parseRetention([]models.RetentionTier{{Within: "0h"}})
+	assert.Error(t, err, "within must be positive")
+
+	_, err = parseRetention([]models.RetentionTier{{Within: "48h", Every: "often"}})
+	assert.Error(t, err, "every must be a duration")
+}
+
+func TestApplyRetention(t *testing.T) {
+	tiers, err := parseRetention([]models.RetentionTier{
+		{Within: "48h"},
+		{Within: "30d", Every: "1d"},
+		{Within: "52w", Every: "1w"},
+	})
+	assert.NoError(t, err)
+
+	// Hourly reports going back 400 days.
+	now := time.Date(2021, 4, 1, 12, 30, 0, 0, time.UTC)
+	reports := make([]models.Report, 0)
+	for hours := 400 * 24; hours >= 0; hours-- {
+		report := models.NewReport()
+		report.Date = now.Add(-time.Duration(hours) * time.Hour)
+		reports = append(reports, report)
+	}
+
+	kept := applyRetention(reports, tiers, now)
+
+	var hourly, daily, weekly int
+	for idx, report := range kept {
+		age := now.Sub(report.Date)
+		switch {
+		case age <= 48*time.Hour:
+			hourly++
+		case age <= 30*24*time.Hour:
+			daily++
+		case age <= 52*7*24*time.Hour:
+			weekly++
+		default:
+			t.Errorf("report from %s is older than every tier", report.Date)
+		}
+		if idx > 0 {
+			assert.True(t, kept[idx-1].Date.Before(report.Date), "kept reports should stay oldest first")
+		}
+	}
+
+	assert.Equal(t, 49, hourly, "every report in the last 48h")
+	assert.InDelta(t, 28, daily, 1, "one report per day for the rest of 30 days")
+	assert.InDelta(t, 48, weekly, 1, "one report per week for the rest of the year")
+
+	// Applying it again later must not thin out what we already kept.
+	assert.Equal(t, kept, applyRetention(kept, tiers, now), "retention should be stable")
+}
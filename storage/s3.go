@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/zanloy/bms-api/models"
+)
+
+const defaultS3Key = "bms-reports.json.gz"
+
+// staleS3ObjectError means the object changed between reading the reports and
+// writing them back.
+var staleS3ObjectError = fmt.Errorf("s3 object changed while updating")
+
+// s3Backend keeps all reports gzipped in a single object in an S3 compatible
+// object store.
+//
+// Updates check the object's ETag right before writing it and start over if
+// another writer got there first. The client can't make the put itself
+// conditional, so two writers that check at the same moment can still lose a
+// report. Only one replica should save reports to an s3 backend.
+type s3Backend struct {
+	cfg    models.S3Config // What the client was built from.
+	client *minio.Client
+	bucket string
+	key    string
+}
+
+func newS3Backend(cfg models.S3Config) (*s3Backend, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("storage.s3.endpoint and storage.s3.bucket must be set to use the s3 storage backend.")
+	}
+
+	// Use static credentials if given, otherwise the standard AWS env vars.
+	var creds *credentials.Credentials
+	if cfg.AccessKeyID != "" {
+		creds = credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+	} else {
+		creds = credentials.NewEnvAWS()
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: !cfg.Insecure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create s3 client for [%s]: %w", cfg.Endpoint, err)
+	}
+
+	key := cfg.Key
+	if key == "" {
+		key = defaultS3Key
+	}
+
+	return &s3Backend{cfg: cfg, client: client, bucket: cfg.Bucket, key: key}, nil
+}
+
+func (sb *s3Backend) Load() ([]models.Report, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reports, _, err := sb.read(ctx)
+	return reports, err
+}
+
+// read returns the stored reports and the ETag of the object they came from.
+// The ETag is empty if nothing has been saved yet.
+func (sb *s3Backend) read(ctx context.Context) ([]models.Report, string, error) {
+	// Core gives us the data and the ETag from the same request.
+	body, info, _, err := minio.Core{Client: sb.client}.GetObject(ctx, sb.bucket, sb.key, minio.GetObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			// Nothing has been saved yet.
+			return []models.Report{}, "", nil
+		}
+		return []models.Report{}, "", fmt.Errorf("Failed to get s3 object [%s/%s]: %w", sb.bucket, sb.key, err)
+	}
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return []models.Report{}, "", fmt.Errorf("Failed to get s3 object [%s/%s]: %w", sb.bucket, sb.key, err)
+	}
+
+	reports, err := decodeReports(data)
+	if err != nil {
+		return []models.Report{}, "", fmt.Errorf("Failed to decode reports from s3 object [%s/%s]: %w", sb.bucket, sb.key, err)
+	}
+
+	return reports, info.ETag, nil
+}
+
+// etag returns the current ETag of the object, or empty if it doesn't exist.
+func (sb *s3Backend) etag(ctx context.Context) (string, error) {
+	info, err := sb.client.StatObject(ctx, sb.bucket, sb.key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return "", nil
+		}
+		return "", fmt.Errorf("Failed to stat s3 object [%s/%s]: %w", sb.bucket, sb.key, err)
+	}
+	return info.ETag, nil
+}
+
+func (sb *s3Backend) Update(fn func([]models.Report) ([]models.Report, error)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	return retry.OnError(updateBackoff, isStaleS3Object, func() error {
+		return sb.update(ctx, fn)
+	})
+}
+
+// update is a single attempt at a read-modify-write of the reports. It returns
+// staleS3ObjectError if the object changed since it was read.
+func (sb *s3Backend) update(ctx context.Context, fn func([]models.Report) ([]models.Report, error)) error {
+	reports, prevETag, err := sb.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	if reports, err = fn(reports); err != nil {
+		return err
+	}
+
+	reportBytes, err := encodeReports(reports)
+	if err != nil {
+		return fmt.Errorf("Error while trying to encode reports: %w", err)
+	}
+
+	if currETag, err := sb.etag(ctx); err != nil {
+		return err
+	} else if currETag != prevETag {
+		return staleS3ObjectError
+	}
+
+	_, err = sb.client.PutObject(ctx, sb.bucket, sb.key, bytes.NewReader(reportBytes), int64(len(reportBytes)), minio.PutObjectOptions{ContentType: "application/gzip"})
+	if err != nil {
+		return fmt.Errorf("Failed to put s3 object [%s/%s]: %w", sb.bucket, sb.key, err)
+	}
+
+	return nil
+}
+
+func isStaleS3Object(err error) bool {
+	return errors.Is(err, staleS3ObjectError)
+}
@@ -3,43 +3,89 @@ package storage // import github.com/zanloy/bms-api/storage
 import (
 	"bytes"
 	"compress/gzip"
-	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	corev1 "k8s.io/api/core/v1"
-	k8errors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-
+	"github.com/rs/zerolog/log"
 	"github.com/zanloy/bms-api/config"
-	"github.com/zanloy/bms-api/kubernetes"
 	"github.com/zanloy/bms-api/models"
 )
 
 const SecretName = "bms-reports"
 
+/* Errors */
+var (
+	ReportNotFoundError        = fmt.Errorf("Report not found.")
+	ScheduledReportExistsError = fmt.Errorf("A report was already saved for this scheduled run.")
+)
+
+// A Backend is somewhere we can keep reports. Which one is used is set by
+// storage.backend in the config.
+type Backend interface {
+	// Load returns all stored reports, oldest first. If nothing has been stored
+	// yet it returns an empty list.
+	Load() ([]models.Report, error)
+	// Update passes the stored reports through fn and stores the result.
+	Update(fn func([]models.Report) ([]models.Report, error)) error
+}
+
+const (
+	BackendSecret     = "secret"
+	BackendConfigMap  = "configmap"
+	BackendFilesystem = "filesystem"
+	BackendS3         = "s3"
+)
+
 var (
-	b64   = base64.StdEncoding
+	logger = log.With().
+		Timestamp().
+		Str("component", "storage").
+		Logger()
 	mutex = sync.Mutex{}
+	// s3Cached is the last s3 backend built by newBackend. It is guarded by
+	// mutex.
+	s3Cached *s3Backend
 )
 
+// DeleteReport will remove the report stored for date. Reports are matched by
+// their unix timestamp since that is how they are addressed over the API.
+func DeleteReport(date time.Time) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	backend, err := newBackend(config.Storage())
+	if err != nil {
+		return err
+	}
+
+	return backend.Update(func(reports []models.Report) ([]models.Report, error) {
+		idx := findReport(reports, date)
+		if idx == -1 {
+			return reports, ReportNotFoundError
+		}
+		return append(reports[:idx], reports[idx+1:]...), nil
+	})
+}
+
+// GetReport will return the report stored for date. Reports are matched by
+// their unix timestamp since that is how they are addressed over the API.
 func GetReport(date time.Time) (models.Report, error) {
 	reports, err := loadReports()
 	if err != nil {
 		return models.Report{}, err
 	}
 
-	for _, report := range reports {
-		if report.Date == date {
-			return report, nil
-		}
+	if idx := findReport(reports, date); idx != -1 {
+		return reports[idx], nil
 	}
 
-	return models.Report{}, fmt.Errorf("Report not found.")
+	return models.Report{}, ReportNotFoundError
 }
 
 func GetReports() ([]models.Report, error) {
@@ -60,71 +106,85 @@ func ListReports() ([]models.ReportSummary, error) {
 	return summaries, nil
 }
 
-func SaveReport(report models.Report) error {
+// SaveReport will store the report and return the key it can be retrieved by.
+// Only one report is kept per scheduled run so every replica running the
+// schedule doesn't save its own. If one was already saved its key is returned
+// with ScheduledReportExistsError.
+func SaveReport(report models.Report) (string, error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Get secret
-	secret, err := kubernetes.Clientset.CoreV1().Secrets(config.Namespace()).Get(ctx, SecretName, metav1.GetOptions{})
+	backend, err := newBackend(config.Storage())
 	if err != nil {
-		if k8errors.IsNotFound(err) {
-			var reports = []models.Report{report}
-			reportBytes, err := encodeReports(reports)
-			if err != nil {
-				// This should never happen. Good luck getting 100% code coverage.
-				return fmt.Errorf("Error while trying to encode reports: %w", err)
-			}
+		return "", err
+	}
 
-			secret = &corev1.Secret{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: SecretName,
-				},
-				Data: map[string][]byte{"reports": reportBytes},
-			}
+	report.Key = reportKey(report)
 
-			if _, err := kubernetes.Clientset.CoreV1().Secrets(config.Namespace()).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
-				return fmt.Errorf("Failed to save new reports secret: %w", err)
+	err = backend.Update(func(reports []models.Report) ([]models.Report, error) {
+		if !report.Scheduled.IsZero() {
+			for _, existing := range reports {
+				if existing.Scheduled.Equal(report.Scheduled) {
+					report.Key = existing.Key
+					return reports, ScheduledReportExistsError
+				}
 			}
+		}
 
-			return nil // We're done.
+		// Only keep one report per key.
+		if idx := findReport(reports, report.Date); idx != -1 {
+			reports[idx] = report
 		} else {
-			return fmt.Errorf("Failed to pull reports from kubernetes: %w", err)
+			reports = append(reports, report)
 		}
+		return pruneReports(reports)
+	})
+	if errors.Is(err, ScheduledReportExistsError) {
+		return report.Key, err
+	} else if err != nil {
+		return "", fmt.Errorf("Failed to save reports: %w", err)
 	}
 
-	var reports []models.Report
-
-	if data, ok := secret.Data["reports"]; ok {
-		reports, err = decodeReports(data)
-		if err != nil {
-			return fmt.Errorf("Failed to decode reports from secret: %w", err)
-		}
-	} else {
-		return fmt.Errorf("Failed to load reports: No 'reports' field in secret [%s].", SecretName)
-	}
+	return report.Key, nil
+}
 
-	reports = append(reports, report)
-	reports, _ = pruneReports(reports)
+/* Private funcs */
 
-	reportBytes, err := encodeReports(reports)
-	if err != nil {
-		return fmt.Errorf("Failed to save reports: Error while trying to encode reports: %w", err)
+// newBackend returns the Backend described by cfg. The caller must hold
+// mutex.
+func newBackend(cfg models.StorageConfig) (Backend, error) {
+	name := cfg.Name
+	if name == "" {
+		name = SecretName
 	}
-	secret.Data["reports"] = reportBytes
 
-	_, err = kubernetes.Clientset.CoreV1().Secrets(config.Namespace()).Update(ctx, secret, metav1.UpdateOptions{})
-	if err != nil {
-		return fmt.Errorf("Failed to save reports: Error while trying to update k8 secret: %w", err)
+	maxChunks := cfg.MaxChunks
+	if maxChunks <= 0 {
+		maxChunks = defaultMaxChunks
 	}
 
-	return nil
+	switch strings.ToLower(cfg.Backend) {
+	case "", BackendSecret:
+		return &kubeBackend{objects: secretObjects{namespace: config.Namespace()}, name: name, maxChunks: maxChunks}, nil
+	case BackendConfigMap:
+		return &kubeBackend{objects: configMapObjects{namespace: config.Namespace()}, name: name, maxChunks: maxChunks}, nil
+	case BackendFilesystem:
+		return newFileBackend(cfg.Path)
+	case BackendS3:
+		// The s3 client is kept until its config changes.
+		if s3Cached == nil || s3Cached.cfg != cfg.S3 {
+			backend, err := newS3Backend(cfg.S3)
+			if err != nil {
+				return nil, err
+			}
+			s3Cached = backend
+		}
+		return s3Cached, nil
+	default:
+		return nil, fmt.Errorf("Unknown storage backend [%s]. Valid backends are %s, %s, %s, and %s.", cfg.Backend, BackendSecret, BackendConfigMap, BackendFilesystem, BackendS3)
+	}
 }
 
-/* Private funcs */
-
 func decodeReports(data []byte) ([]models.Report, error) {
 	// gunzip data
 	r, err := gzip.NewReader(bytes.NewReader(data))
@@ -166,38 +226,57 @@ func encodeReports(reports []models.Report) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// findReport returns the index of the report matching date or -1 if not found.
+func findReport(reports []models.Report, date time.Time) int {
+	for idx, report := range reports {
+		if report.Date.Unix() == date.Unix() {
+			return idx
+		}
+	}
+	return -1
+}
+
+// reportKey returns the key a report is addressed by over the API.
+func reportKey(report models.Report) string {
+	return strconv.FormatInt(report.Date.Unix(), 10)
+}
+
 func loadReports() ([]models.Report, error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Get secret
-	secret, err := kubernetes.Clientset.CoreV1().Secrets(config.Namespace()).Get(ctx, SecretName, metav1.GetOptions{})
+	backend, err := newBackend(config.Storage())
 	if err != nil {
 		return []models.Report{}, err
 	}
 
-	if b, ok := secret.Data["reports"]; ok {
-		reports, err := decodeReports(b)
+	reports, err := backend.Load()
+	if err != nil {
+		return []models.Report{}, fmt.Errorf("Failed to load reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+// pruneReports applies the retention tiers from the config, if any, and then
+// caps the number of reports at max_reports. When retention tiers are set the
+// cap only applies if max_reports was set explicitly.
+func pruneReports(reports []models.Report) ([]models.Report, error) {
+	if input := config.Retention(); len(input) > 0 {
+		tiers, err := parseRetention(input)
 		if err != nil {
-			return []models.Report{}, fmt.Errorf("Failed to load reports: %w", err)
+			return reports, err
 		}
+		reports = applyRetention(reports, tiers, time.Now())
 
-		return reports, nil
-	} else {
-		return []models.Report{}, fmt.Errorf("Failed to load reports: No 'reports' field in reports secret.")
+		if config.Config.MaxReports == 0 {
+			return reports, nil
+		}
 	}
-}
-
-func pruneReports(reports []models.Report) ([]models.Report, bool) {
-	var max = config.MaxReports()
 
-	if diff := len(reports) - max; diff > 0 {
+	if diff := len(reports) - config.MaxReports(); diff > 0 {
 		reports = reports[diff:]
-		return reports, true
 	}
 
-	return reports, false
+	return reports, nil
 }
@@ -0,0 +1,335 @@
+package storage_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zanloy/bms-api/config"
+	"github.com/zanloy/bms-api/kubernetes"
+	"github.com/zanloy/bms-api/models"
+	"github.com/zanloy/bms-api/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func genReport(date time.Time) models.Report {
+	report := models.NewReport()
+	report.Date = date
+	return report
+}
+
+// fakeS3 is just enough of an S3 server to get, stat, and put objects.
+// beforeStat, if set, is called with the objects before a stat is answered.
+type fakeS3 struct {
+	mutex      sync.Mutex
+	objects    map[string][]byte
+	beforeStat func(objects map[string][]byte)
+}
+
+func (fs *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if r.Method == http.MethodHead && fs.beforeStat != nil {
+			fs.beforeStat(fs.objects)
+		}
+		data, ok := fs.objects[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			}
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, md5.Sum(data)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body = decodeAWSChunked(body)
+		}
+		fs.objects[r.URL.Path] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, md5.Sum(body)))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeAWSChunked strips the chunk headers from a streaming signed upload.
+func decodeAWSChunked(body []byte) []byte {
+	var result bytes.Buffer
+	reader := bufio.NewReader(bytes.NewReader(body))
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		size, err := strconv.ParseInt(strings.SplitN(strings.TrimSpace(header), ";", 2)[0], 16, 64)
+		if err != nil || size == 0 {
+			break
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			break
+		}
+		result.Write(chunk)
+		reader.ReadString('\n')
+	}
+	return result.Bytes()
+}
+
+func TestReportHistory(t *testing.T) {
+	s3server := httptest.NewServer(&fakeS3{objects: map[string][]byte{}})
+	defer s3server.Close()
+
+	cases := []struct {
+		desc    string
+		storage models.StorageConfig
+	}{{
+		desc:    "with the default secret backend",
+		storage: models.StorageConfig{},
+	}, {
+		desc:    "with the configmap backend",
+		storage: models.StorageConfig{Backend: storage.BackendConfigMap},
+	}, {
+		desc:    "with the filesystem backend",
+		storage: models.StorageConfig{Backend: storage.BackendFilesystem, Path: t.TempDir()},
+	}, {
+		desc: "with the s3 backend",
+		storage: models.StorageConfig{Backend: storage.BackendS3, S3: models.S3Config{
+			Endpoint:        strings.TrimPrefix(s3server.URL, "http://"),
+			Bucket:          "bms",
+			Region:          "us-east-1",
+			AccessKeyID:     "access",
+			SecretAccessKey: "secret",
+			Insecure:        true,
+		}},
+	}}
+
+	for _, testcase := range cases {
+		kubernetes.InitWithClientset(fake.NewSimpleClientset())
+		config.Config.Storage = testcase.storage
+		testReportHistory(t, testcase.desc)
+	}
+
+	config.Config.Storage = models.StorageConfig{}
+}
+
+func testReportHistory(t *testing.T, desc string) {
+	// Nothing saved yet should be an empty list, not an error.
+	summaries, err := storage.ListReports()
+	assert.NoError(t, err, "listing before any save %s", desc)
+	assert.Empty(t, summaries, "listing before any save %s", desc)
+
+	first := time.Unix(1600000000, 500)
+	second := time.Unix(1600003600, 500)
+	key, err := storage.SaveReport(genReport(first))
+	assert.NoError(t, err, "saving first report %s", desc)
+	assert.Equal(t, "1600000000", key, "saving first report %s", desc)
+	_, err = storage.SaveReport(genReport(second))
+	assert.NoError(t, err, "saving second report %s", desc)
+	// Saving again with the same key should replace, not duplicate.
+	_, err = storage.SaveReport(genReport(second))
+	assert.NoError(t, err, "saving second report again %s", desc)
+
+	summaries, err = storage.ListReports()
+	assert.NoError(t, err, "listing after save %s", desc)
+	if assert.Len(t, summaries, 2, "listing after save %s", desc) {
+		assert.Equal(t, first.Unix(), summaries[0].Timestamp)
+		assert.Equal(t, second.Unix(), summaries[1].Timestamp)
+	}
+
+	// Reports are addressed by their unix timestamp so sub-second precision
+	// should not matter.
+	report, err := storage.GetReport(time.Unix(second.Unix(), 0))
+	assert.NoError(t, err, "getting report by timestamp %s", desc)
+	assert.Equal(t, second.Unix(), report.Date.Unix(), "getting report by timestamp %s", desc)
+	assert.Equal(t, "1600003600", report.Key, "getting report by timestamp %s", desc)
+
+	_, err = storage.GetReport(time.Unix(42, 0))
+	assert.ErrorIs(t, err, storage.ReportNotFoundError, "getting missing report %s", desc)
+
+	assert.NoError(t, storage.DeleteReport(time.Unix(first.Unix(), 0)), "deleting report %s", desc)
+	assert.ErrorIs(t, storage.DeleteReport(time.Unix(first.Unix(), 0)), storage.ReportNotFoundError, "deleting report twice %s", desc)
+
+	summaries, err = storage.ListReports()
+	assert.NoError(t, err, "listing after delete %s", desc)
+	if assert.Len(t, summaries, 1, "listing after delete %s", desc) {
+		assert.Equal(t, second.Unix(), summaries[0].Timestamp)
+	}
+
+	// Only the first replica to save a scheduled run should be kept.
+	slot := time.Unix(1600007200, 0)
+	scheduled := genReport(slot.Add(time.Second))
+	scheduled.Scheduled = slot
+	key, err = storage.SaveReport(scheduled)
+	assert.NoError(t, err, "saving scheduled report %s", desc)
+	assert.Equal(t, "1600007201", key, "saving scheduled report %s", desc)
+	duplicate := genReport(slot.Add(5 * time.Second))
+	duplicate.Scheduled = slot
+	key, err = storage.SaveReport(duplicate)
+	assert.ErrorIs(t, err, storage.ScheduledReportExistsError, "saving scheduled report twice %s", desc)
+	assert.Equal(t, "1600007201", key, "saving scheduled report twice %s", desc)
+
+	summaries, err = storage.ListReports()
+	assert.NoError(t, err, "listing after scheduled save %s", desc)
+	assert.Len(t, summaries, 2, "listing after scheduled save %s", desc)
+}
+
+func TestUnknownBackend(t *testing.T) {
+	config.Config.Storage = models.StorageConfig{Backend: "floppy"}
+	defer func() { config.Config.Storage = models.StorageConfig{} }()
+
+	_, err := storage.SaveReport(genReport(time.Now()))
+	assert.Error(t, err)
+}
+
+// genBigReport returns a report that compresses to roughly size bytes.
+func genBigReport(date time.Time, size int) models.Report {
+	report := genReport(date)
+	noise := make([]byte, size)
+	rand.New(rand.NewSource(date.Unix())).Read(noise)
+	report.Errors = append(report.Errors, base64.StdEncoding.EncodeToString(noise))
+	return report
+}
+
+// secretNames returns the names of the secrets in our namespace with the
+// random part of chunk names (bms-reports-<random>-0) replaced by "chunk".
+func secretNames(t *testing.T) []string {
+	secrets, err := kubernetes.Clientset.CoreV1().Secrets("bms").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	chunkName := regexp.MustCompile(`^bms-reports-[a-z0-9]{5}-`)
+	names := make([]string, len(secrets.Items))
+	for idx, secret := range secrets.Items {
+		names[idx] = chunkName.ReplaceAllString(secret.Name, "chunk-")
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestChunkedReports(t *testing.T) {
+	kubernetes.InitWithClientset(fake.NewSimpleClientset())
+
+	// Three ~500KiB reports need two chunks.
+	for hour := int64(0); hour < 3; hour++ {
+		_, err := storage.SaveReport(genBigReport(time.Unix(1600000000+hour*3600, 0), 500*1024))
+		assert.NoError(t, err, "saving big report")
+	}
+	assert.Equal(t, []string{"bms-reports", "chunk-0", "chunk-1"}, secretNames(t), "secrets after saving big reports")
+
+	summaries, err := storage.ListReports()
+	assert.NoError(t, err, "listing chunked reports")
+	assert.Len(t, summaries, 3, "listing chunked reports")
+
+	report, err := storage.GetReport(time.Unix(1600003600, 0))
+	assert.NoError(t, err, "getting chunked report")
+	assert.Equal(t, genBigReport(time.Unix(1600003600, 0), 500*1024).Errors, report.Errors, "getting chunked report")
+
+	// Shrinking back to a single chunk should clean up the unused one.
+	assert.NoError(t, storage.DeleteReport(time.Unix(1600000000, 0)), "deleting chunked report")
+	assert.NoError(t, storage.DeleteReport(time.Unix(1600003600, 0)), "deleting chunked report")
+	assert.Equal(t, []string{"bms-reports", "chunk-0"}, secretNames(t), "secrets after deleting big reports")
+
+	// Going past max_chunks should fail without writing anything.
+	config.Config.Storage = models.StorageConfig{MaxChunks: 1}
+	defer func() { config.Config.Storage = models.StorageConfig{} }()
+	_, err = storage.SaveReport(genBigReport(time.Unix(1600010800, 0), 500*1024))
+	if assert.Error(t, err, "saving past max_chunks") {
+		assert.Contains(t, err.Error(), "storage.max_chunks is 1", "saving past max_chunks")
+	}
+	summaries, err = storage.ListReports()
+	assert.NoError(t, err, "listing after failed save")
+	assert.Len(t, summaries, 1, "listing after failed save")
+}
+
+func TestS3ConcurrentWriter(t *testing.T) {
+	s3 := &fakeS3{objects: map[string][]byte{}}
+	s3server := httptest.NewServer(s3)
+	defer s3server.Close()
+
+	config.Config.Storage = models.StorageConfig{Backend: storage.BackendS3, S3: models.S3Config{
+		Endpoint:        strings.TrimPrefix(s3server.URL, "http://"),
+		Bucket:          "bms",
+		Region:          "us-east-1",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		Insecure:        true,
+	}}
+	defer func() { config.Config.Storage = models.StorageConfig{} }()
+
+	_, err := storage.SaveReport(genReport(time.Unix(1600000000, 0)))
+	assert.NoError(t, err, "saving the first report")
+
+	// Another replica saves a report after we read the object but before we
+	// write it back.
+	s3.mutex.Lock()
+	s3.beforeStat = func(objects map[string][]byte) {
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		json.NewEncoder(writer).Encode([]models.Report{genReport(time.Unix(1600000000, 0)), genReport(time.Unix(1600003600, 0))})
+		writer.Close()
+		objects["/bms/bms-reports.json.gz"] = buffer.Bytes()
+		s3.beforeStat = nil
+	}
+	s3.mutex.Unlock()
+
+	_, err = storage.SaveReport(genReport(time.Unix(1600007200, 0)))
+	assert.NoError(t, err, "saving over the other replica")
+
+	summaries, err := storage.ListReports()
+	assert.NoError(t, err, "listing reports")
+	assert.Len(t, summaries, 3, "the other replica's report should be kept")
+}
+
+func TestLegacyReportsSecret(t *testing.T) {
+	// Reports saved before chunking was added were kept in the 'reports' key
+	// of the bms-reports secret.
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	json.NewEncoder(writer).Encode([]models.Report{genReport(time.Unix(1600000000, 0))})
+	writer.Close()
+
+	kubernetes.InitWithClientset(fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bms-reports", Namespace: "bms"},
+		Data:       map[string][]byte{"reports": buffer.Bytes()},
+	}))
+
+	summaries, err := storage.ListReports()
+	assert.NoError(t, err, "listing legacy reports")
+	assert.Len(t, summaries, 1, "listing legacy reports")
+
+	_, err = storage.SaveReport(genReport(time.Unix(1600003600, 0)))
+	assert.NoError(t, err, "saving over legacy reports")
+
+	summaries, err = storage.ListReports()
+	assert.NoError(t, err, "listing converted reports")
+	assert.Len(t, summaries, 2, "listing converted reports")
+	assert.Equal(t, []string{"bms-reports", "chunk-0"}, secretNames(t), "secrets after converting legacy reports")
+}
@@ -12,11 +12,16 @@ import (
 
 var (
 	Objects = []string{
+		"cronjob",
 		"daemonset",
 		"deployment",
+		"job",
 		"namespace",
 		"node",
+		"persistentvolume",
+		"persistentvolumeclaim",
 		"pod",
+		"service",
 		"statefulset",
 		"url",
 	}